@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+// autosaveInterval is how often the temp file is checked for saves
+const autosaveInterval = 500 * time.Millisecond
+
+// noteSaver stores the revisions written during an editing session. Each
+// save of the editor becomes a revision so that a crash doesn't lose the
+// session. When the session ends, the autosaved revisions can be squashed
+// into the final revision.
+type noteSaver struct {
+	ctx  context.Context
+	repo orm.Repo
+
+	autosave bool
+	squash   bool
+
+	mu        sync.Mutex
+	rev       orm.NoteRev   // zero until a new note is first saved
+	autosaves []orm.NoteRev // revisions created by autosave
+}
+
+// newNoteSaver creates a saver for an existing note revision, or a new note
+// when rev is the zero value
+func newNoteSaver(ctx context.Context, repo orm.Repo, rev orm.NoteRev) (*noteSaver, error) {
+	ns := &noteSaver{ctx: ctx, repo: repo, rev: rev}
+
+	for key, dst := range map[orm.ConfigKey]*bool{
+		orm.ConfigAutosave:       &ns.autosave,
+		orm.ConfigAutosaveSquash: &ns.squash,
+	} {
+		val, err := repo.GetConfig(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("getting %s config: %w", key, err)
+		}
+		if *dst, err = strconv.ParseBool(val); err != nil {
+			return nil, fmt.Errorf("parsing %s config %q: %w", key, val, err)
+		}
+	}
+
+	return ns, nil
+}
+
+// editorOpts adds the autosave hook to editor options when enabled
+func (ns *noteSaver) editorOpts(opts editorOpts) editorOpts {
+	if ns.autosave {
+		opts.onSave = ns.save
+	}
+	return opts
+}
+
+// save stores an intermediate revision of the note
+func (ns *noteSaver) save(body []byte) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	rev, err := ns.store(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	ns.rev = rev
+	ns.autosaves = append(ns.autosaves, rev)
+	return nil
+}
+
+func (ns *noteSaver) store(body io.Reader) (orm.NoteRev, error) {
+	if ns.rev == (orm.NoteRev{}) {
+		rev, err := ns.repo.NewNote(ns.ctx, body)
+		if err != nil {
+			return orm.NoteRev{}, fmt.Errorf("new note in repo: %w", err)
+		}
+		return rev, nil
+	}
+
+	rev, err := ns.rev.UpdateBlob(ns.ctx, ns.repo, body)
+	if err != nil {
+		return orm.NoteRev{}, fmt.Errorf("updating rev blob: %w", err)
+	}
+	return rev, nil
+}
+
+// finish stores the final revision of the note and squashes the autosaved
+// revisions into it when configured
+func (ns *noteSaver) finish(body io.Reader) (orm.NoteRev, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return orm.NoteRev{}, fmt.Errorf("reading final blob: %w", err)
+	}
+
+	// the last autosave may already hold the final content
+	sum := sha256.Sum256(raw)
+	squash := ns.autosaves
+	if len(ns.autosaves) == 0 || hex.EncodeToString(sum[:]) != ns.rev.SHA256 {
+		rev, err := ns.store(bytes.NewReader(raw))
+		if err != nil {
+			return orm.NoteRev{}, err
+		}
+		ns.rev = rev
+	} else {
+		squash = squash[:len(squash)-1]
+	}
+
+	if ns.squash {
+		if err := ns.repo.SquashNoteRevs(ns.ctx, squash); err != nil {
+			return orm.NoteRev{}, fmt.Errorf("squashing autosaved revs: %w", err)
+		}
+	}
+
+	return ns.rev, nil
+}
+
+// watchFile calls onSave with the contents of the file each time it is
+// written after modTime until stop is closed
+func watchFile(path string, modTime time.Time, initial []byte, onSave func([]byte) error, stop <-chan struct{}, stderr io.Writer) {
+	last := initial
+	ticker := time.NewTicker(autosaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil || fi.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = fi.ModTime()
+
+		body, err := ioutil.ReadFile(path)
+		if err != nil || bytes.Equal(body, last) {
+			continue
+		}
+
+		if err := onSave(body); err != nil {
+			fmt.Fprintf(stderr, "autosave failed: %s\n", err)
+			continue
+		}
+		last = body
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteSaverSquash(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"draft"})
+
+	ns, err := newNoteSaver(ctx, repo, revs[0])
+	require.NoError(t, err)
+	require.True(t, ns.autosave)
+	require.True(t, ns.squash)
+
+	require.NoError(t, ns.save([]byte("autosave 1")))
+
+	// someone else edits the note during the session
+	other, err := ns.rev.UpdateBlob(ctx, repo, bytes.NewBufferString("concurrent edit"))
+	require.NoError(t, err)
+
+	require.NoError(t, ns.save([]byte("autosave 2")))
+	final, err := ns.finish(bytes.NewBufferString("autosave 2"))
+	require.NoError(t, err)
+	require.Equal(t, ns.autosaves[1], final)
+
+	// only the autosaves of the session are squashed
+	history, err := repo.GetNoteRevs(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, []orm.NoteRev{revs[0], other, final}, history)
+}
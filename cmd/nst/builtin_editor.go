@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// builtinEditor is the editor config value that selects the editor
+// embedded in nestable
+const builtinEditor = "builtin"
+
+// errEditCanceled is returned when the user leaves the editor without saving
+var errEditCanceled = errors.New("edit canceled")
+
+var (
+	editorPaneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240"))
+	editorHelpStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	editorLineNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+)
+
+type editorKeyMap struct {
+	save          key.Binding
+	cancel        key.Binding
+	togglePreview key.Binding
+}
+
+func newEditorKeyMap() editorKeyMap {
+	return editorKeyMap{
+		save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		cancel: key.NewBinding(
+			key.WithKeys("esc", "ctrl+c"),
+			key.WithHelp("esc", "cancel"),
+		),
+		togglePreview: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "toggle preview"),
+		),
+	}
+}
+
+// editorModel is a markdown editor with a live preview pane
+type editorModel struct {
+	keys        editorKeyMap
+	input       textarea.Model
+	preview     viewport.Model
+	showPreview bool
+	rendered    string // source of the current preview
+	renderer    *glamour.TermRenderer
+	wrapWidth   int // word wrap width of the renderer
+	width       int
+	height      int
+	top         int // first row of the note shown in the editing pane
+	saved       bool
+}
+
+func newEditorModel(body string, line int) editorModel {
+	input := textarea.New()
+	input.CharLimit = 0
+	input.MaxHeight = 0
+	input.ShowLineNumbers = true
+	input.Prompt = ""
+	input.SetValue(body)
+
+	// SetValue leaves the cursor at the end of the note
+	for input.Line() > 0 {
+		input.CursorUp()
+	}
+	for i := 1; i < line; i++ {
+		input.CursorDown()
+	}
+	input.CursorStart()
+	input.Focus()
+
+	return editorModel{
+		keys:        newEditorKeyMap(),
+		input:       input,
+		preview:     viewport.New(0, 0),
+		showPreview: true,
+	}
+}
+
+func (em editorModel) Init() tea.Cmd { return textarea.Blink }
+
+func (em editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		em.width, em.height = msg.Width, msg.Height
+		em.resize()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, em.keys.save):
+			em.saved = true
+			return em, tea.Quit
+
+		case key.Matches(msg, em.keys.cancel):
+			return em, tea.Quit
+
+		case key.Matches(msg, em.keys.togglePreview):
+			em.showPreview = !em.showPreview
+			em.resize()
+			return em, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	em.input, cmd = em.input.Update(msg)
+	em.follow()
+
+	if em.showPreview && em.input.Value() != em.rendered {
+		em.render()
+	}
+
+	return em, cmd
+}
+
+// paneWidth is the inner width of each pane
+func (em editorModel) paneWidth() int {
+	frame := editorPaneStyle.GetHorizontalFrameSize()
+	if em.showPreview {
+		return em.width/2 - frame
+	}
+	return em.width - frame
+}
+
+func (em *editorModel) resize() {
+	height := em.height - editorPaneStyle.GetVerticalFrameSize() - 1 // help line
+
+	em.input.SetWidth(em.paneWidth())
+	em.input.SetHeight(height)
+
+	em.preview.Width = em.paneWidth()
+	em.preview.Height = height
+	em.follow()
+	em.render()
+}
+
+// follow scrolls the editing pane to keep the cursor in view
+func (em *editorModel) follow() {
+	_, cursor := em.inputRows()
+	height := em.input.Height()
+	if cursor < em.top {
+		em.top = cursor
+	}
+	if height > 0 && cursor >= em.top+height {
+		em.top = cursor - height + 1
+	}
+}
+
+// inputRows soft wraps the note to the editing pane, with line numbers,
+// and returns its rows and the row of the cursor. The textarea wraps lines
+// without regard to markdown, so the pane is drawn from its value and
+// cursor instead.
+func (em editorModel) inputRows() ([]string, int) {
+	lines := strings.Split(em.input.Value(), "\n")
+	gutter := len(strconv.Itoa(len(lines)))
+	if gutter < 3 {
+		gutter = 3
+	}
+	width := em.paneWidth() - gutter - 1
+
+	info := em.input.LineInfo()
+	col := info.StartColumn + info.ColumnOffset
+
+	var (
+		rows   []string
+		cursor int
+		code   bool
+	)
+	for i, line := range lines {
+		fence := isFence(line)
+		wrapped := softWrap(line, width, code && !fence)
+		if fence {
+			code = !code
+		}
+
+		runes := []rune(line)
+		if i == em.input.Line() && col > len(runes) {
+			col = len(runes)
+		}
+		for j, r := range wrapped {
+			number := strings.Repeat(" ", gutter)
+			if j == 0 {
+				number = fmt.Sprintf("%*d", gutter, i+1)
+			}
+
+			text := r.indent + string(runes[r.start:r.end])
+			if i == em.input.Line() && (col < r.end || j == len(wrapped)-1) && col >= r.start {
+				// the cursor is drawn over the rune it is on, or after the line
+				c := em.input.Cursor
+				c.SetChar(" ")
+				after := ""
+				if col < r.end {
+					c.SetChar(string(runes[col]))
+					after = string(runes[col+1 : r.end])
+				}
+				text = r.indent + string(runes[r.start:col]) + c.View() + after
+				cursor = len(rows)
+			}
+
+			row := editorLineNumberStyle.Render(number) + " " + text
+			if pad := em.paneWidth() - lipgloss.Width(row); pad > 0 {
+				row += strings.Repeat(" ", pad)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, cursor
+}
+
+// inputView renders the rows of the note in view in the editing pane
+func (em editorModel) inputView() string {
+	rows, _ := em.inputRows()
+	height := em.input.Height()
+
+	top := em.top
+	if top > len(rows) {
+		top = len(rows)
+	}
+	rows = rows[top:]
+	if len(rows) > height {
+		rows = rows[:height]
+	}
+	for len(rows) < height {
+		rows = append(rows, strings.Repeat(" ", em.paneWidth()))
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+// render refreshes the preview pane. Glamour wraps the markdown to the
+// width of the pane so that lists and code blocks keep their structure.
+// The renderer is only rebuilt when the width of the pane changes.
+func (em *editorModel) render() {
+	em.rendered = em.input.Value()
+	if !em.showPreview || em.preview.Width <= 0 {
+		return
+	}
+
+	if em.renderer == nil || em.wrapWidth != em.preview.Width {
+		r, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle("ascii"),
+			glamour.WithWordWrap(em.preview.Width),
+		)
+		if err != nil {
+			em.preview.SetContent(err.Error())
+			return
+		}
+		em.renderer, em.wrapWidth = r, em.preview.Width
+	}
+
+	out, err := em.renderer.Render(em.rendered)
+	if err != nil {
+		em.preview.SetContent(err.Error())
+		return
+	}
+
+	// follow the cursor through long notes
+	em.preview.SetContent(out)
+	if lines := em.input.LineCount(); lines > 0 {
+		em.preview.SetYOffset(em.preview.TotalLineCount() * em.input.Line() / lines)
+	}
+}
+
+func (em editorModel) View() string {
+	pane := editorPaneStyle.Copy().Width(em.paneWidth())
+	panes := []string{pane.Render(em.inputView())}
+	if em.showPreview {
+		panes = append(panes, pane.Render(em.preview.View()))
+	}
+
+	help := editorHelpStyle.Render(fmt.Sprintf("%s • %s • %s",
+		helpText(em.keys.save), helpText(em.keys.cancel), helpText(em.keys.togglePreview)))
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, panes...),
+		help,
+	)
+}
+
+func helpText(b key.Binding) string {
+	return b.Help().Key + " " + b.Help().Desc
+}
+
+// runBuiltinEditor edits the blob in the embedded terminal editor
+func runBuiltinEditor(blob io.Reader, opts editorOpts, stdin io.Reader, stdout io.Writer) (io.ReadCloser, error) {
+	body, err := ioutil.ReadAll(blob)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob: %w", err)
+	}
+
+	final, err := tea.NewProgram(
+		newEditorModel(string(body), opts.line),
+		tea.WithAltScreen(),
+		tea.WithInput(stdin),
+		tea.WithOutput(stdout),
+	).Run()
+	if err != nil {
+		return nil, fmt.Errorf("running builtin editor: %w", err)
+	}
+
+	return final.(editorModel).result()
+}
+
+// result returns the edited note, or errEditCanceled when the user left the
+// editor without saving
+func (em editorModel) result() (io.ReadCloser, error) {
+	if !em.saved {
+		return nil, errEditCanceled
+	}
+
+	return ioutil.NopCloser(bytes.NewBufferString(em.input.Value())), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftWrap(t *testing.T) {
+	show := func(line string, rows []wrappedRow) []string {
+		runes := []rune(line)
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.indent+string(runes[r.start:r.end]))
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name  string
+		line  string
+		width int
+		code  bool
+		rows  []string
+	}{
+		{name: "short", line: "drain the node", width: 20, rows: []string{"drain the node"}},
+		{name: "empty", line: "", width: 20, rows: []string{""}},
+		{name: "paragraph", line: "drain the node and reboot it", width: 12, rows: []string{"drain the ", "node and ", "reboot it"}},
+		{name: "long word", line: "kubernetes", width: 4, rows: []string{"kube", "rnet", "es"}},
+		{name: "list item", line: "- drain the node and reboot it", width: 14, rows: []string{"- drain the ", "  node and ", "  reboot it"}},
+		{name: "nested ordered item", line: "  12. drain the node", width: 13, rows: []string{"  12. drain ", "      the ", "      node"}},
+		{name: "task", line: "* [x] drain the node", width: 14, rows: []string{"* [x] drain ", "      the node"}},
+		{name: "quote", line: "> > drain the node and reboot", width: 14, rows: []string{"> > drain the ", "> > node and ", "> > reboot"}},
+		{name: "quoted list item", line: "> - drain the node", width: 12, rows: []string{"> - drain ", ">   the node"}},
+		{name: "deep indent", line: "        - drain the node", width: 12, rows: []string{"        - ", "drain the ", "node"}},
+		{name: "code", line: "- kubectl drain node", width: 8, code: true, rows: []string{"- kubect", "l drain ", "node"}},
+		{name: "wide runes", line: "日本語の説明です", width: 8, rows: []string{"日本語の", "説明です"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.rows, show(tc.line, softWrap(tc.line, tc.width, tc.code)))
+		})
+	}
+}
+
+func TestEditorModel(t *testing.T) {
+	update := func(em editorModel, msg tea.Msg) (editorModel, tea.Cmd) {
+		m, cmd := em.Update(msg)
+		return m.(editorModel), cmd
+	}
+	quits := func(cmd tea.Cmd) bool {
+		if cmd == nil {
+			return false
+		}
+		_, ok := cmd().(tea.QuitMsg)
+		return ok
+	}
+
+	newModel := func() editorModel {
+		em := newEditorModel("# Runbook\n\n- drain the node before the upgrade, then reboot it\n", 3)
+		em, _ = update(em, tea.WindowSizeMsg{Width: 80, Height: 24})
+		return em
+	}
+
+	t.Run("save", func(t *testing.T) {
+		em := newModel()
+		em, _ = update(em, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		em, cmd := update(em, tea.KeyMsg{Type: tea.KeyCtrlS})
+		require.True(t, quits(cmd))
+
+		note, err := em.result()
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(note)
+		require.NoError(t, err)
+		require.Equal(t, "# Runbook\n\nx- drain the node before the upgrade, then reboot it\n", string(body))
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		em := newModel()
+		em, _ = update(em, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		em, cmd := update(em, tea.KeyMsg{Type: tea.KeyEsc})
+		require.True(t, quits(cmd))
+
+		_, err := em.result()
+		require.ErrorIs(t, err, errEditCanceled)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		em := newModel()
+		frame := editorPaneStyle.GetHorizontalFrameSize()
+		require.True(t, em.showPreview)
+		require.Equal(t, 40-frame, em.preview.Width)
+		require.Contains(t, em.preview.View(), "Runbook")
+		require.Equal(t, 80, lipgloss.Width(em.View()))
+
+		em, cmd := update(em, tea.KeyMsg{Type: tea.KeyCtrlP})
+		require.Nil(t, cmd)
+		require.False(t, em.showPreview)
+		require.Equal(t, 80-frame, em.paneWidth())
+		require.Equal(t, 80, lipgloss.Width(em.View()))
+
+		em, _ = update(em, tea.WindowSizeMsg{Width: 60, Height: 10})
+		require.Equal(t, 60-frame, em.paneWidth())
+		require.Equal(t, 60, lipgloss.Width(em.View()))
+		require.Equal(t, 10, lipgloss.Height(em.View()))
+
+		em, _ = update(em, tea.KeyMsg{Type: tea.KeyCtrlP})
+		require.True(t, em.showPreview)
+		require.Equal(t, 30-frame, em.preview.Width)
+		require.Contains(t, em.preview.View(), "Runbook")
+	})
+
+	t.Run("soft wrap", func(t *testing.T) {
+		em := newModel()
+		// the list item continues under its text
+		rows, cursor := em.inputRows()
+		require.Equal(t, 2, cursor)
+		require.Equal(t, "  3 - drain the node before the", strings.TrimRight(rows[2], " "))
+		require.Equal(t, "      upgrade, then reboot it", strings.TrimRight(rows[3], " "))
+	})
+
+	t.Run("scroll", func(t *testing.T) {
+		em := newEditorModel(strings.Repeat("line\n", 30), 1)
+		em, _ = update(em, tea.WindowSizeMsg{Width: 80, Height: 10})
+		require.Zero(t, em.top)
+
+		for i := 0; i < 20; i++ {
+			em, _ = update(em, tea.KeyMsg{Type: tea.KeyDown})
+		}
+		_, cursor := em.inputRows()
+		require.Equal(t, 20, cursor)
+		require.Equal(t, cursor-em.input.Height()+1, em.top)
+		require.Contains(t, em.inputView(), " 21 ")
+		require.NotContains(t, em.inputView(), "  1 ")
+	})
+}
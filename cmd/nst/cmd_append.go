@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+type appendCmd struct {
+	repo      orm.Repo
+	noteID    *int64
+	to        *string
+	msg       *string
+	timestamp *bool
+}
+
+func newAppendCmd(repo orm.Repo) subCmd {
+	return &appendCmd{repo: repo}
+}
+
+func (_ *appendCmd) Help() string {
+	return `Append text from stdin or an arg to the end of an existing note.`
+}
+
+func (_ *appendCmd) Names() []string {
+	return []string{"append", "a"}
+}
+
+func (ac *appendCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("append", flag.ExitOnError)
+	ac.noteID = fs.Int64("id", 0, "note ID you want to append to")
+	ac.to = fs.String("to", "", "header (first line) of the note you want to append to")
+	ac.msg = fs.String("m", "", "text to append instead of reading stdin")
+	ac.timestamp = fs.Bool("ts", false, "prefix the appended text with a timestamp")
+	return fs
+}
+
+func (ac *appendCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		rev orm.NoteRev
+		err error
+	)
+
+	switch {
+	case *ac.noteID != 0:
+		rev, err = ac.repo.GetCurrentNoteRev(ctx, *ac.noteID)
+		if err != nil {
+			return fmt.Errorf("getting current note rev for ID %d: %w", *ac.noteID, err)
+		}
+	case *ac.to != "":
+		rev, err = ac.repo.FindNoteByHeader(ctx, *ac.to)
+		if err != nil {
+			return fmt.Errorf("finding note to append to: %w", err)
+		}
+	default:
+		return errors.New("specify the note to append to with -id or -to")
+	}
+
+	entry := []byte(*ac.msg)
+	if *ac.msg == "" {
+		if !isPiped(r) {
+			return errors.New("provide text to append with -m or stdin")
+		}
+
+		entry, err = ioutil.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	if *ac.timestamp {
+		entry = append([]byte(orm.Now().Format(timestampLayout)+" "), entry...)
+	}
+
+	newRev, err := rev.AppendBlob(ctx, ac.repo, bytes.NewReader(entry))
+	if err != nil {
+		return fmt.Errorf("appending to note %d: %w", rev.ID, err)
+	}
+
+	_, err = fmt.Fprintln(w, newRev.SHA256)
+	return err
+}
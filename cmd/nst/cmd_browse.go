@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -15,6 +16,13 @@ import (
 
 var (
 	appStyle = lipgloss.NewStyle().Padding(1, 2)
+
+	sidebarWidth = 30
+	sidebarStyle = lipgloss.NewStyle().
+			Width(sidebarWidth).
+			MarginRight(2).
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderRight(true)
 )
 
 type listKeyMap struct {
@@ -24,6 +32,8 @@ type listKeyMap struct {
 	togglePagination key.Binding
 	toggleHelpMenu   key.Binding
 	insertItem       key.Binding
+	toggleFocus      key.Binding
+	toggleRelated    key.Binding
 }
 
 func newListKeyMap() listKeyMap {
@@ -52,14 +62,56 @@ func newListKeyMap() listKeyMap {
 			key.WithKeys("H"),
 			key.WithHelp("H", "toggle help"),
 		),
+		toggleFocus: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "saved searches"),
+		),
+		toggleRelated: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "toggle related notes"),
+		),
 	}
 }
 
 type browseModel struct {
+	ctx          context.Context
+	repo         orm.Repo
 	list         list.Model
 	choices      []orm.NoteRev
 	keys         listKeyMap
 	delegateKeys delegateKeyMap
+
+	// sidebar lists the saved searches that filter the notes, it is only
+	// shown when there are saved searches
+	sidebar      list.Model
+	sidebarFocus bool
+
+	// relatedTo is the note whose related notes are listed, zero when
+	// listing all notes
+	relatedTo int64
+}
+
+// savedItem is a saved search in the browse sidebar. The zero value shows
+// all notes.
+type savedItem struct {
+	search  orm.SavedSearch
+	changed int
+}
+
+func (si savedItem) FilterValue() string { return si.search.Name }
+
+func (si savedItem) Title() string {
+	if si.search.Name == "" {
+		return "All notes"
+	}
+	return "@" + si.search.Name
+}
+
+func (si savedItem) Description() string {
+	if si.search.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d changed since last run", si.changed)
 }
 
 type revItem struct {
@@ -148,6 +200,46 @@ func loadBrowseModel(ctx context.Context, repo orm.Repo) (browseModel, error) {
 		return browseModel{}, fmt.Errorf("getting notes for browse model: %w", err)
 	}
 
+	keys := newListKeyMap()
+	noteList := list.New(revItems(ctx, repo, revs), newRevItemDelegate(newDelegateKeyMap()), 0, 0)
+	noteList.Title = "Notes"
+	noteList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.toggleRelated, keys.toggleFocus}
+	}
+
+	searches, err := repo.GetSavedSearches(ctx)
+	if err != nil {
+		return browseModel{}, fmt.Errorf("getting saved searches for browse model: %w", err)
+	}
+
+	var sidebarItems []list.Item
+	if len(searches) > 0 {
+		sidebarItems = append(sidebarItems, savedItem{})
+	}
+	for _, ss := range searches {
+		changed, err := ss.Changed(ctx, repo)
+		if err != nil {
+			return browseModel{}, err
+		}
+		sidebarItems = append(sidebarItems, savedItem{search: ss, changed: changed})
+	}
+
+	sidebar := list.New(sidebarItems, list.NewDefaultDelegate(), sidebarWidth, 0)
+	sidebar.Title = "Saved searches"
+	sidebar.SetShowHelp(false)
+	sidebar.SetFilteringEnabled(false)
+
+	return browseModel{
+		ctx:          ctx,
+		repo:         repo,
+		list:         noteList,
+		keys:         keys,
+		delegateKeys: newDelegateKeyMap(),
+		sidebar:      sidebar,
+	}, nil
+}
+
+func revItems(ctx context.Context, repo orm.Repo, revs []orm.NoteRev) []list.Item {
 	items := make([]list.Item, len(revs))
 	for i, r := range revs {
 		items[i] = revItem{
@@ -156,15 +248,92 @@ func loadBrowseModel(ctx context.Context, repo orm.Repo) (browseModel, error) {
 			repo: repo,
 		}
 	}
+	return items
+}
 
-	noteList := list.New(items, newRevItemDelegate(newDelegateKeyMap()), 0, 0)
-	noteList.Title = "Notes"
+// hasSidebar reports whether there are saved searches to show
+func (bm browseModel) hasSidebar() bool {
+	return len(bm.sidebar.Items()) > 0
+}
 
-	return browseModel{
-		list:         noteList,
-		keys:         newListKeyMap(),
-		delegateKeys: newDelegateKeyMap(),
-	}, nil
+// runSavedSearch lists the notes matching the saved search, or all notes
+// for the zero value
+func (bm browseModel) runSavedSearch(si savedItem) (browseModel, tea.Cmd) {
+	var (
+		revs []orm.NoteRev
+		err  error
+	)
+	if si.search.Name == "" {
+		revs, err = bm.repo.GetNotes(bm.ctx)
+	} else {
+		revs, err = bm.savedSearchRevs(si.search.Name)
+	}
+	var status tea.Cmd
+	if errors.Is(err, errNoMatch) {
+		// show the empty list with the suggestions, if any
+		status, err = bm.list.NewStatusMessage(err.Error()), nil
+	}
+	if err != nil {
+		return bm, bm.list.NewStatusMessage(err.Error())
+	}
+
+	bm.list.Title = si.Title()
+	bm.sidebarFocus = false
+	bm.relatedTo = 0
+	return bm, tea.Batch(bm.list.SetItems(revItems(bm.ctx, bm.repo, revs)), status)
+}
+
+// toggleRelated lists the notes related to the selected note, or all notes
+// again when related notes are listed
+func (bm browseModel) toggleRelated() (browseModel, tea.Cmd) {
+	if bm.relatedTo != 0 {
+		return bm.runSavedSearch(savedItem{})
+	}
+
+	ri, ok := bm.list.SelectedItem().(revItem)
+	if !ok {
+		return bm, nil
+	}
+
+	related, err := bm.repo.RelatedNotes(bm.ctx, ri.nr.ID, 20)
+	if err != nil {
+		return bm, bm.list.NewStatusMessage(err.Error())
+	}
+	if len(related) == 0 {
+		return bm, bm.list.NewStatusMessage("No related notes")
+	}
+
+	revs := make([]orm.NoteRev, len(related))
+	for i, rn := range related {
+		revs[i] = rn.NoteRev
+	}
+
+	bm.list.Title = "Related to " + ri.Title()
+	bm.relatedTo = ri.nr.ID
+	return bm, bm.list.SetItems(revItems(bm.ctx, bm.repo, revs))
+}
+
+func (bm browseModel) savedSearchRevs(name string) ([]orm.NoteRev, error) {
+	query, err := bm.repo.ResolveSearch(bm.ctx, "@"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := bm.repo.FullTextSearch(bm.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, noMatchError(bm.ctx, bm.repo, query)
+	}
+
+	revs := make([]orm.NoteRev, len(results))
+	for i, r := range results {
+		if revs[i], err = r.GetNoteRev(bm.ctx, bm.repo); err != nil {
+			return nil, err
+		}
+	}
+	return revs, nil
 }
 
 func (bm browseModel) Init() tea.Cmd { return tea.EnterAltScreen }
@@ -175,6 +344,10 @@ func (bm browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		h, v := appStyle.GetFrameSize()
+		if bm.hasSidebar() {
+			bm.sidebar.SetSize(sidebarWidth, msg.Height-v)
+			h += sidebarStyle.GetHorizontalFrameSize() + sidebarWidth
+		}
 		bm.list.SetSize(msg.Width-h, msg.Height-v)
 
 	case tea.KeyMsg:
@@ -183,6 +356,23 @@ func (bm browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			break
 		}
 
+		if bm.hasSidebar() && key.Matches(msg, bm.keys.toggleFocus) {
+			bm.sidebarFocus = !bm.sidebarFocus
+			return bm, nil
+		}
+
+		if bm.sidebarFocus {
+			if msg.Type == tea.KeyEnter {
+				if si, ok := bm.sidebar.SelectedItem().(savedItem); ok {
+					return bm.runSavedSearch(si)
+				}
+			}
+
+			var cmd tea.Cmd
+			bm.sidebar, cmd = bm.sidebar.Update(msg)
+			return bm, cmd
+		}
+
 		switch {
 		case key.Matches(msg, bm.keys.toggleSpinner):
 			cmd := bm.list.ToggleSpinner()
@@ -207,6 +397,9 @@ func (bm browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			bm.list.SetShowHelp(!bm.list.ShowHelp())
 			return bm, nil
 
+		case key.Matches(msg, bm.keys.toggleRelated):
+			return bm.toggleRelated()
+
 			//		case key.Matches(msg, bm.keys.insertItem):
 			//			bm.delegateKeys.remove.SetEnabled(true)
 			//			newItem := bm.itemGenerator.next()
@@ -225,7 +418,15 @@ func (bm browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (bm browseModel) View() string {
-	return bm.list.View()
+	if !bm.hasSidebar() {
+		return bm.list.View()
+	}
+
+	sidebar := bm.sidebar.View()
+	if !bm.sidebarFocus {
+		sidebar = lipgloss.NewStyle().Faint(true).Render(sidebar)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, sidebarStyle.Render(sidebar), bm.list.View())
 }
 
 type browseCmd struct {
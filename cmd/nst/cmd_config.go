@@ -11,8 +11,9 @@ import (
 )
 
 type getConfigCmd struct {
-	repo orm.Repo
-	key  *string
+	repo   orm.Repo
+	key    *string
+	output *outputFlags
 }
 
 func newGetConfigCmd(repo orm.Repo) subCmd {
@@ -30,10 +31,36 @@ func (_ *getConfigCmd) Names() []string {
 func (gcc *getConfigCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("set-config", flag.ExitOnError)
 	gcc.key = fs.String("key", "", "config key to get")
+	gcc.output = addOutputFlags(fs, "")
 	return fs
 }
 
 func (gcc *getConfigCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if gcc.output.enabled() {
+		entries, err := gcc.repo.GetConfigEntries(ctx)
+		if err != nil {
+			return fmt.Errorf("getting config entries: %w", err)
+		}
+
+		if *gcc.key != "" {
+			var selected []orm.ConfigEntry
+			for _, e := range entries {
+				if e.Key == *gcc.key {
+					selected = append(selected, e)
+				}
+			}
+			entries = selected
+		}
+		if len(entries) == 0 {
+			return errNoMatch
+		}
+
+		return gcc.output.print(w, []string{"KEY", "VALUE", "DESCRIPTION"}, len(entries), func(i int) (interface{}, []string) {
+			e := entries[i]
+			return e, []string{e.Key, e.Value, e.Description}
+		})
+	}
+
 	if *gcc.key == "" {
 		keys, err := gcc.repo.GetConfigKeys(ctx)
 		if err != nil {
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+type dupesCmd struct {
+	repo      orm.Repo
+	threshold *float64
+	merge     *bool
+	output    *outputFlags
+}
+
+func newDupesCmd(repo orm.Repo) subCmd {
+	return &dupesCmd{repo: repo}
+}
+
+func (_ *dupesCmd) Help() string {
+	return `List groups of notes with the same or nearly the same text, and optionally merge them.`
+}
+
+func (_ *dupesCmd) Names() []string {
+	return []string{"dupes"}
+}
+
+func (dc *dupesCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("dupes", flag.ExitOnError)
+	dc.threshold = fs.Float64("threshold", 0.8, "minimum similarity of near duplicates, from 0 to 1")
+	dc.merge = fs.Bool("merge", false, "interactively merge each group into one of its notes and trash the others")
+	dc.output = addOutputFlags(fs, formatTable)
+	return fs
+}
+
+// dupeRecord is the output of a note in a group of duplicates
+type dupeRecord struct {
+	noteRecord
+	Group      int
+	Exact      bool
+	Similarity float64
+}
+
+func (dr dupeRecord) cells() []string {
+	similarity := fmt.Sprintf("%.0f%%", dr.Similarity*100)
+	if dr.Exact {
+		similarity = "exact"
+	}
+	return []string{
+		fmt.Sprint(dr.Group),
+		fmt.Sprint(dr.ID),
+		dr.Timestamp.Local().Format(timestampLayout),
+		similarity,
+		dr.Header,
+	}
+}
+
+var dupeColumns = []string{"GROUP", "ID", "MODIFIED", "SIMILARITY", "HEADER"}
+
+func (dc *dupesCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *dc.threshold < 0 || *dc.threshold > 1 {
+		return fmt.Errorf("invalid threshold %v, expected a similarity from 0 to 1", *dc.threshold)
+	}
+
+	groups, err := dc.repo.FindDuplicates(ctx, *dc.threshold)
+	if err != nil {
+		return fmt.Errorf("finding duplicates: %w", err)
+	}
+	if len(groups) == 0 {
+		return errNoMatch
+	}
+
+	var records []dupeRecord
+	for i, g := range groups {
+		for _, n := range g.Notes {
+			nr, err := newNoteRecord(ctx, dc.repo, n)
+			if err != nil {
+				return err
+			}
+			records = append(records, dupeRecord{
+				noteRecord: nr,
+				Group:      i + 1,
+				Exact:      g.Exact,
+				Similarity: g.Similarity,
+			})
+		}
+	}
+
+	if !*dc.merge {
+		return dc.output.print(w, dupeColumns, len(records), func(i int) (interface{}, []string) {
+			return records[i], records[i].cells()
+		})
+	}
+
+	return dc.mergeGroups(ctx, groups, records, r, w)
+}
+
+// mergeGroups prompts for the note to keep in each group and merges the
+// other notes of the group into it
+func (dc *dupesCmd) mergeGroups(ctx context.Context, groups []orm.DuplicateGroup, records []dupeRecord, r io.Reader, w io.Writer) error {
+	answers := bufio.NewReader(r)
+
+	for i, g := range groups {
+		var (
+			group []dupeRecord
+			ids   []string
+		)
+		for _, rec := range records {
+			if rec.Group == i+1 {
+				group = append(group, rec)
+				ids = append(ids, fmt.Sprint(rec.ID))
+			}
+		}
+
+		fmt.Fprintln(os.Stderr)
+		if err := dc.output.print(os.Stderr, dupeColumns, len(group), func(i int) (interface{}, []string) {
+			return group[i], group[i].cells()
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "keep note [%s], s to skip or q to quit (default %s): ", strings.Join(ids, "/"), ids[0])
+
+		answer, err := answers.ReadString('\n')
+		if errors.Is(err, io.EOF) && answer == "" {
+			// no more answers, leave the remaining groups as they are
+			return nil
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		answer = strings.TrimSpace(answer)
+		switch answer {
+		case "s":
+			continue
+		case "q":
+			return nil
+		case "":
+			answer = ids[0]
+		}
+
+		keep, err := strconv.ParseInt(answer, 10, 64)
+		if err != nil || !containsNote(g.Notes, keep) {
+			return fmt.Errorf("note %q is not in group %d", answer, i+1)
+		}
+
+		// merge the revisions that were shown, so that notes edited since
+		// conflict instead of being overwritten
+		var keepRev orm.NoteRev
+		for _, n := range g.Notes {
+			if n.ID == keep {
+				keepRev = n
+			}
+		}
+		for _, n := range g.Notes {
+			if n.ID == keep {
+				continue
+			}
+			if keepRev, err = dc.repo.MergeNoteRevs(ctx, keepRev, n); err != nil {
+				return fmt.Errorf("merging note %d into note %d: %w", n.ID, keep, err)
+			}
+			fmt.Fprintf(w, "merged note %d into note %d and trashed it\n", n.ID, keep)
+		}
+	}
+
+	return nil
+}
+
+func containsNote(notes []orm.NoteRev, id int64) bool {
+	for _, n := range notes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
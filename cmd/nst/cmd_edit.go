@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ type editCmd struct {
 	repo   orm.Repo
 	noteID *int64
 	search *string
+	ext    *string
 }
 
 func newEditCmd(repo orm.Repo) subCmd {
@@ -31,14 +33,16 @@ func (_ *editCmd) Names() []string {
 func (ec *editCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("edit", flag.ExitOnError)
 	ec.noteID = fs.Int64("id", 0, "note ID you want to edit")
-	ec.search = fs.String("s", "", "full text search term to filter results")
+	ec.search = fs.String("s", "", "full text search term, or @name of a saved search, to filter results")
+	ec.ext = fs.String("ext", "", "file extension to edit the note with (overrides editor_ext config)")
 	return fs
 }
 
 func (ec *editCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 	var (
-		rev orm.NoteRev
-		err error
+		rev  orm.NoteRev
+		opts = editorOpts{ext: *ec.ext}
+		err  error
 	)
 
 	if *ec.noteID != 0 {
@@ -49,15 +53,29 @@ func (ec *editCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 	}
 
 	if *ec.search != "" && rev == (orm.NoteRev{}) {
-		results, err := ec.repo.FullTextSearch(ctx, *ec.search)
+		query, err := ec.repo.ResolveSearch(ctx, *ec.search)
+		if err != nil {
+			return err
+		}
+
+		results, err := ec.repo.FullTextSearch(ctx, query)
 		if err != nil {
 			return fmt.Errorf("full text search with term %q: %w", *ec.search, err)
 		}
+		if len(results) == 0 {
+			return noMatchError(ctx, ec.repo, query)
+		}
 
-		rev, err = selectFTSResults(ctx, ec.repo, results)
+		result, err := selectFTSResults(ctx, ec.repo, results)
 		if err != nil {
 			return fmt.Errorf("selecting search results: %w", err)
 		}
+
+		rev, err = result.GetNoteRev(ctx, ec.repo)
+		if err != nil {
+			return fmt.Errorf("fetch note rev for search result: %w", err)
+		}
+		opts.line = result.Line
 	}
 
 	if rev == (orm.NoteRev{}) {
@@ -71,20 +89,35 @@ func (ec *editCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 
 	}
 
-	blobReader, err := rev.GetReader(ctx, ec.repo)
+	return editNote(ctx, ec.repo, rev, opts, r, w)
+}
+
+// editNote opens the note revision in the editor and saves the result as
+// a new revision
+func editNote(ctx context.Context, repo orm.Repo, rev orm.NoteRev, opts editorOpts, r io.Reader, w io.Writer) error {
+	blobReader, err := rev.GetReader(ctx, repo)
 	if err != nil {
 		return fmt.Errorf("get reader for rev pick: %w", err)
 	}
 
-	newBlob, err := runEditor(ctx, ec.repo, blobReader, r, w, os.Stderr)
+	saver, err := newNoteSaver(ctx, repo, rev)
+	if err != nil {
+		return fmt.Errorf("preparing note saver: %w", err)
+	}
+
+	newBlob, err := runEditor(ctx, repo, blobReader, saver.editorOpts(opts), r, w, os.Stderr)
+	if errors.Is(err, errEditCanceled) {
+		_, err = fmt.Fprintln(os.Stderr, "edit canceled, note unchanged")
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("run external editor: %w", err)
 	}
 	defer newBlob.Close()
 
-	newRev, err := rev.UpdateBlob(ctx, ec.repo, newBlob)
+	newRev, err := saver.finish(newBlob)
 	if err != nil {
-		return fmt.Errorf("updating rev blob: %w", err)
+		return fmt.Errorf("saving edited note: %w", err)
 	}
 
 	_, err = fmt.Fprintln(w, newRev.SHA256)
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+type grepCmd struct {
+	repo       orm.Repo
+	fs         *flag.FlagSet
+	ignoreCase *bool
+	fixed      *bool
+	history    *bool
+	output     *outputFlags
+}
+
+func newGrepCmd(repo orm.Repo) subCmd {
+	return &grepCmd{repo: repo}
+}
+
+func (_ *grepCmd) Help() string {
+	return `Print the lines of notes matching a regular expression, like git grep.`
+}
+
+func (_ *grepCmd) Names() []string {
+	return []string{"grep", "g"}
+}
+
+func (gc *grepCmd) FlagSet() *flag.FlagSet {
+	gc.fs = flag.NewFlagSet("grep", flag.ExitOnError)
+	gc.fs.Usage = func() {
+		out := gc.fs.Output()
+		fmt.Fprintln(out, "Usage: nst grep [options] <pattern>")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, `The pattern is a Go regular expression matched against each line, e.g. '10\.0\.3\.' or 'err_code=5\d\d',`)
+		fmt.Fprintln(out, `or a substring with -F, e.g. -F '10.0.3.*'.`)
+		fmt.Fprintln(out, `Matching lines are printed as <note ID>:<line>:<text>.`)
+		fmt.Fprintln(out)
+		gc.fs.PrintDefaults()
+	}
+	gc.ignoreCase = gc.fs.Bool("i", false, "ignore case")
+	gc.fixed = gc.fs.Bool("F", false, "match the pattern as a fixed string rather than a regular expression")
+	gc.history = gc.fs.Bool("history", false, "scan every revision of the notes, printed as <note ID>:<rev>:<line>:<text>")
+	gc.output = addOutputFlags(gc.fs, "")
+	return gc.fs
+}
+
+// grepRecord is the output of a line matching a grep pattern
+type grepRecord struct {
+	orm.NoteRev
+	Line int
+	Text string
+}
+
+func (gc *grepCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	pattern := strings.Join(gc.fs.Args(), " ")
+	if pattern == "" {
+		gc.fs.Usage()
+		return errors.New("grep requires a pattern")
+	}
+	if *gc.fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if *gc.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	matches, err := gc.repo.Grep(ctx, pattern, orm.GrepOptions{History: *gc.history})
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return errNoMatch
+	}
+
+	if gc.output.enabled() {
+		columns := []string{"ID", "REV", "LINE", "TEXT"}
+		return gc.output.print(w, columns, len(matches), func(i int) (interface{}, []string) {
+			m := matches[i]
+			return grepRecord{NoteRev: m.NoteRev, Line: m.Line, Text: m.Text}, []string{
+				fmt.Sprint(m.ID),
+				shortSHA(m.SHA256),
+				fmt.Sprint(m.Line),
+				m.Text,
+			}
+		})
+	}
+
+	// the pattern is valid since Grep compiled it
+	re := regexp.MustCompile(pattern)
+	color := isTerminal(w)
+
+	for _, m := range matches {
+		text := m.Text
+		if color {
+			text = re.ReplaceAllStringFunc(text, func(match string) string {
+				return ansiHighlight + match + ansiReset
+			})
+		}
+
+		if *gc.history {
+			fmt.Fprintf(w, "%d:%s:%d:%s\n", m.ID, shortSHA(m.SHA256), m.Line, text)
+		} else {
+			fmt.Fprintf(w, "%d:%d:%s\n", m.ID, m.Line, text)
+		}
+	}
+
+	return nil
+}
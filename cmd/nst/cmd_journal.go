@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/pokstad/nestable/orm"
+)
+
+type todayCmd struct {
+	repo orm.Repo
+	msg  *string
+}
+
+func newTodayCmd(repo orm.Repo) subCmd {
+	return &todayCmd{repo: repo}
+}
+
+func (_ *todayCmd) Help() string {
+	return `Open today's journal note, creating it from the journal template if needed.`
+}
+
+func (_ *todayCmd) Names() []string {
+	return []string{"today", "t"}
+}
+
+func (tc *todayCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("today", flag.ExitOnError)
+	tc.msg = fs.String("m", "", "append a timestamped line to today's note instead of opening the editor")
+	return fs
+}
+
+func (tc *todayCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	now := orm.Now()
+
+	rev, err := todaysNote(ctx, tc.repo, now)
+	if err != nil {
+		return err
+	}
+
+	if *tc.msg == "" {
+		return editNote(ctx, tc.repo, rev, editorOpts{}, r, w)
+	}
+
+	entry := fmt.Sprintf("- %s %s", now.Local().Format("15:04"), *tc.msg)
+	newRev, err := rev.AppendBlob(ctx, tc.repo, bytes.NewBufferString(entry))
+	if err != nil {
+		return fmt.Errorf("appending to today's note: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, newRev.SHA256)
+	return err
+}
+
+// todaysNote finds the journal note for the day of now, or creates it
+// from the journal template
+func todaysNote(ctx context.Context, repo orm.Repo, now time.Time) (orm.NoteRev, error) {
+	day := orm.DayKey(now)
+
+	rev, err := repo.GetJournalNote(ctx, day)
+	if err == nil {
+		return rev, nil
+	}
+	if !errors.Is(err, orm.ErrNotFound) {
+		return orm.NoteRev{}, fmt.Errorf("getting journal note for %s: %w", day, err)
+	}
+
+	tmpl, err := repo.GetConfig(ctx, orm.ConfigJournalTmpl)
+	if err != nil {
+		return orm.NoteRev{}, fmt.Errorf("getting journal template: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	if err := orm.RenderTemplate(body, tmpl, orm.NewTemplateData()); err != nil {
+		return orm.NoteRev{}, fmt.Errorf("rendering journal template: %w", err)
+	}
+
+	rev, err = repo.NewJournalNote(ctx, day, body)
+	if err != nil {
+		return orm.NoteRev{}, fmt.Errorf("creating journal note for %s: %w", day, err)
+	}
+
+	return rev, nil
+}
+
+type journalCmd struct {
+	repo orm.Repo
+}
+
+func newJournalCmd(repo orm.Repo) subCmd {
+	return &journalCmd{repo: repo}
+}
+
+func (_ *journalCmd) Help() string {
+	return `Pick a day from the journal to view.`
+}
+
+func (_ *journalCmd) Names() []string {
+	return []string{"journal", "j"}
+}
+
+func (jc *journalCmd) FlagSet() *flag.FlagSet {
+	return flag.NewFlagSet("journal", flag.ExitOnError)
+}
+
+func (jc *journalCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	days, err := jc.repo.JournalDays(ctx)
+	if err != nil {
+		return fmt.Errorf("listing journal days: %w", err)
+	}
+	if len(days) == 0 {
+		return errors.New("the journal is empty, start it with `nst today`")
+	}
+
+	written := map[string]bool{}
+	for _, d := range days {
+		written[d.Day] = true
+	}
+
+	idx, err := fuzzyfinder.Find(days,
+		func(i int) string {
+			day, err := time.ParseInLocation(orm.DayLayout, days[i].Day, time.Local)
+			if err != nil {
+				return days[i].Day
+			}
+			return day.Format("2006-01-02 Mon")
+		},
+		fuzzyfinder.WithHeader("Select a day to view"),
+		fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
+			if i == -1 {
+				return ""
+			}
+
+			day, err := time.ParseInLocation(orm.DayLayout, days[i].Day, time.Local)
+			if err != nil {
+				panic(err)
+			}
+
+			bReader, err := days[i].GetReader(ctx, jc.repo)
+			if err != nil {
+				panic(err)
+			}
+
+			raw, err := ioutil.ReadAll(bReader)
+			if err != nil {
+				panic(err)
+			}
+
+			md, err := glamour.RenderBytes(raw, "ascii")
+			if err != nil {
+				panic(err)
+			}
+
+			return monthCalendar(day, written) + "\n" + string(md)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("selecting journal day: %w", err)
+	}
+
+	bReader, err := days[idx].GetReader(ctx, jc.repo)
+	if err != nil {
+		return fmt.Errorf("getting blob reader: %w", err)
+	}
+
+	raw, err := ioutil.ReadAll(bReader)
+	if err != nil {
+		return fmt.Errorf("reading blob: %w", err)
+	}
+
+	out, err := glamour.RenderBytes(raw, "ascii")
+	if err != nil {
+		return fmt.Errorf("rendering blob: %w", err)
+	}
+
+	fmt.Fprint(w, string(out))
+
+	return nil
+}
+
+// monthCalendar renders the month of the selected day like cal(1). Days
+// with a journal note are marked with a '*' and the selected day is
+// surrounded by brackets.
+func monthCalendar(selected time.Time, written map[string]bool) string {
+	b := &strings.Builder{}
+
+	first := time.Date(selected.Year(), selected.Month(), 1, 0, 0, 0, 0, time.Local)
+	title := first.Format("January 2006")
+	fmt.Fprintf(b, "%*s\n", (28+len(title))/2, title)
+	b.WriteString(" Su  Mo  Tu  We  Th  Fr  Sa\n")
+
+	b.WriteString(strings.Repeat("    ", int(first.Weekday())))
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		left, right := " ", " "
+		if d.Day() == selected.Day() {
+			left, right = "[", "]"
+		} else if written[orm.DayKey(d)] {
+			right = "*"
+		}
+		fmt.Fprintf(b, "%s%2d%s", left, d.Day(), right)
+
+		if d.Weekday() == time.Saturday {
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), " ") + "\n"
+}
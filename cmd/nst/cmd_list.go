@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+type listCmd struct {
+	repo   orm.Repo
+	query  *string
+	output *outputFlags
+}
+
+func newListCmd(repo orm.Repo) subCmd {
+	return &listCmd{repo: repo}
+}
+
+func (_ *listCmd) Help() string {
+	return `List all notes, most recently modified first, for use in scripts.`
+}
+
+func (_ *listCmd) Names() []string {
+	return []string{"list", "ls"}
+}
+
+func (lc *listCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	lc.query = fs.String("q", "", noteQueryUsage)
+	lc.output = addOutputFlags(fs, formatTable)
+	return fs
+}
+
+func (lc *listCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	notes, err := lc.listNotes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		return errNoMatch
+	}
+
+	records := make([]noteRecord, len(notes))
+	for i, n := range notes {
+		if records[i], err = newNoteRecord(ctx, lc.repo, n); err != nil {
+			return err
+		}
+	}
+
+	return lc.output.print(w, noteColumns, len(records), func(i int) (interface{}, []string) {
+		return records[i], records[i].cells()
+	})
+}
+
+func (lc *listCmd) listNotes(ctx context.Context) ([]orm.NoteRev, error) {
+	if *lc.query == "" {
+		notes, err := lc.repo.GetNotes(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing notes: %w", err)
+		}
+		return notes, nil
+	}
+
+	q, err := parseQuery(*lc.query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := lc.repo.QueryNotes(ctx, q, orm.SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("querying notes: %w", err)
+	}
+
+	notes := make([]orm.NoteRev, len(results))
+	for i, r := range results {
+		notes[i] = r.NoteRev
+	}
+	return notes, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -14,6 +15,8 @@ import (
 type newCmd struct {
 	repo orm.Repo
 	msg  *string
+	ext  *string
+	tmpl *string
 }
 
 func newNewCmd(repo orm.Repo) subCmd {
@@ -21,7 +24,7 @@ func newNewCmd(repo orm.Repo) subCmd {
 }
 
 func (_ *newCmd) Help() string {
-	return `Create a new note with your editor of choice.`
+	return `Create a new note with your editor of choice, or from stdin when piped.`
 }
 
 func (_ *newCmd) Names() []string {
@@ -31,15 +34,42 @@ func (_ *newCmd) Names() []string {
 func (nc *newCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("new", flag.ExitOnError)
 	nc.msg = fs.String("m", "", "Provide note as an arg without invoking external editor")
+	nc.tmpl = fs.String("t", "", "name of the template to start the note from")
+	nc.ext = fs.String("ext", "", "file extension to edit the note with (overrides editor_ext config)")
 	return fs
 }
 
 func (nc *newCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
-	var blob io.Reader = bytes.NewBufferString(*nc.msg)
+	saver, err := newNoteSaver(ctx, nc.repo, orm.NoteRev{})
+	if err != nil {
+		return fmt.Errorf("preparing note saver: %w", err)
+	}
+
+	var skeleton []byte
+	if *nc.tmpl != "" {
+		// fields can't be prompted for when stdin holds the note
+		prompts := r
+		if isPiped(r) {
+			prompts = bytes.NewReader(nil)
+		}
+
+		skeleton, err = renderNoteTemplate(ctx, nc.repo, *nc.tmpl, prompts)
+		if err != nil {
+			return err
+		}
+	}
 
-	if *nc.msg == "" {
-		var err error
-		editorBlob, err := runEditor(ctx, nc.repo, bytes.NewReader(nil), r, w, os.Stderr)
+	var blob io.Reader = io.MultiReader(bytes.NewReader(skeleton), bytes.NewBufferString(*nc.msg))
+
+	if *nc.msg == "" && isPiped(r) {
+		blob = io.MultiReader(bytes.NewReader(skeleton), r)
+	} else if *nc.msg == "" {
+		opts := saver.editorOpts(editorOpts{ext: *nc.ext})
+		editorBlob, err := runEditor(ctx, nc.repo, bytes.NewReader(skeleton), opts, r, w, os.Stderr)
+		if errors.Is(err, errEditCanceled) {
+			_, err = fmt.Fprintln(os.Stderr, "new note canceled")
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("run external editor: %w", err)
 		}
@@ -48,7 +78,7 @@ func (nc *newCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 		blob = editorBlob
 	}
 
-	nr, err := nc.repo.NewNote(ctx, blob)
+	nr, err := saver.finish(blob)
 	if err != nil {
 		return fmt.Errorf("new note in repo: %w", err)
 	}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+func newSavedCmd(repo orm.Repo) subCmd {
+	return &groupCmd{
+		names: []string{"saved", "sv"},
+		help:  `Manage saved searches, run them anywhere a search is accepted with @name.`,
+		actions: []subCmd{
+			&savedAddCmd{repo: repo},
+			&savedListCmd{repo: repo},
+			&savedRunCmd{repo: repo},
+			&savedRemoveCmd{repo: repo},
+		},
+	}
+}
+
+type savedAddCmd struct {
+	repo  orm.Repo
+	name  *string
+	query *string
+}
+
+func (_ *savedAddCmd) Help() string {
+	return `Save a full text search query under a name, or replace its query.`
+}
+
+func (_ *savedAddCmd) Names() []string {
+	return []string{"add"}
+}
+
+func (sac *savedAddCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("saved add", flag.ExitOnError)
+	sac.name = fs.String("name", "", "name of the saved search")
+	sac.query = fs.String("s", "", "full text search query")
+	return fs
+}
+
+func (sac *savedAddCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *sac.name == "" || *sac.query == "" {
+		return errors.New("name the saved search with -name and give its query with -s")
+	}
+	if strings.ContainsAny(*sac.name, " \t@") {
+		return fmt.Errorf("saved search name %q can't contain spaces or @", *sac.name)
+	}
+
+	return sac.repo.SaveSearch(ctx, orm.SavedSearch{Name: *sac.name, Query: *sac.query})
+}
+
+type savedListCmd struct {
+	repo   orm.Repo
+	output *outputFlags
+}
+
+func (_ *savedListCmd) Help() string {
+	return `List the saved searches with the number of matching notes changed since they last ran.`
+}
+
+func (_ *savedListCmd) Names() []string {
+	return []string{"list", "ls"}
+}
+
+func (slc *savedListCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("saved list", flag.ExitOnError)
+	slc.output = addOutputFlags(fs, formatTable)
+	return fs
+}
+
+// savedRecord is the output of a saved search
+type savedRecord struct {
+	orm.SavedSearch
+	Changed int
+}
+
+func (sr savedRecord) cells() []string {
+	lastRun := "never"
+	if !sr.LastRun.IsZero() {
+		lastRun = sr.LastRun.Format(timestampLayout)
+	}
+	return []string{"@" + sr.Name, sr.Query, lastRun, fmt.Sprint(sr.Changed)}
+}
+
+func (slc *savedListCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	searches, err := slc.repo.GetSavedSearches(ctx)
+	if err != nil {
+		return err
+	}
+	if len(searches) == 0 {
+		return errNoMatch
+	}
+
+	records := make([]savedRecord, len(searches))
+	for i, ss := range searches {
+		changed, err := ss.Changed(ctx, slc.repo)
+		if err != nil {
+			return err
+		}
+		records[i] = savedRecord{SavedSearch: ss, Changed: changed}
+	}
+
+	columns := []string{"NAME", "QUERY", "LAST RUN", "CHANGED"}
+	return slc.output.print(w, columns, len(records), func(i int) (interface{}, []string) {
+		return records[i], records[i].cells()
+	})
+}
+
+type savedRunCmd struct {
+	repo   orm.Repo
+	name   *string
+	output *outputFlags
+}
+
+func (_ *savedRunCmd) Help() string {
+	return `Print the notes matching a saved search, like nst search @name.`
+}
+
+func (_ *savedRunCmd) Names() []string {
+	return []string{"run"}
+}
+
+func (src *savedRunCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("saved run", flag.ExitOnError)
+	src.name = fs.String("name", "", "name of the saved search to run")
+	src.output = addOutputFlags(fs, "")
+	return fs
+}
+
+func (src *savedRunCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *src.name == "" {
+		return errors.New("name the saved search to run with -name")
+	}
+
+	opts := orm.SearchOptions{}
+	if !src.output.enabled() {
+		opts = highlightMatches(opts, w)
+	}
+
+	records, err := fullTextSearch(ctx, src.repo, "@"+*src.name, opts)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errNoMatch
+	}
+
+	if src.output.enabled() {
+		return src.output.printSearchRecords(w, records)
+	}
+	return printSearchMatches(ctx, src.repo, w, records, false)
+}
+
+type savedRemoveCmd struct {
+	repo orm.Repo
+	name *string
+}
+
+func (_ *savedRemoveCmd) Help() string {
+	return `Remove a saved search.`
+}
+
+func (_ *savedRemoveCmd) Names() []string {
+	return []string{"rm"}
+}
+
+func (src *savedRemoveCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("saved rm", flag.ExitOnError)
+	src.name = fs.String("name", "", "name of the saved search to remove")
+	return fs
+}
+
+func (src *savedRemoveCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	return src.repo.DeleteSavedSearch(ctx, *src.name)
+}
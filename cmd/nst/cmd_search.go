@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+// ANSI escape codes to highlight search matches in a terminal
+const (
+	ansiHighlight = "\x1b[1;33m"
+	ansiReset     = "\x1b[0m"
+)
+
+type searchCmd struct {
+	repo    orm.Repo
+	fs      *flag.FlagSet
+	limit   *int
+	offset  *int
+	history *bool
+	query   *string
+	rank    *string
+	output  *outputFlags
+}
+
+func newSearchCmd(repo orm.Repo) subCmd {
+	return &searchCmd{repo: repo}
+}
+
+func (_ *searchCmd) Help() string {
+	return `Print the notes matching a full text search query, best match first.`
+}
+
+func (_ *searchCmd) Names() []string {
+	return []string{"search", "s"}
+}
+
+func (sc *searchCmd) FlagSet() *flag.FlagSet {
+	sc.fs = flag.NewFlagSet("search", flag.ExitOnError)
+	sc.fs.Usage = func() {
+		out := sc.fs.Output()
+		fmt.Fprintln(out, "Usage: nst search [options] <query>")
+		fmt.Fprintln(out, "       nst search [options] -q <note query>")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, `The query uses the SQLite FTS5 syntax, e.g. 'mind AND map', '"exact phrase"' or 'nest*',`)
+		fmt.Fprintln(out, `or runs the saved search named by '@name'.`)
+		fmt.Fprintln(out, `The note query combines text with filters, e.g. 'text:"k8s" modified:>2026-01-01 sort:-modified'.`)
+		fmt.Fprintln(out)
+		sc.fs.PrintDefaults()
+	}
+	sc.limit = sc.fs.Int("limit", 20, "maximum number of results, 0 for all")
+	sc.offset = sc.fs.Int("offset", 0, "number of results to skip, for paging with -limit")
+	sc.history = sc.fs.Bool("history", false, "search every revision of the notes, requires the history_index config")
+	sc.query = sc.fs.String("q", "", noteQueryUsage)
+	sc.rank = sc.fs.String("rank", "", `override the ranking weights of the config, e.g. "recency=2,halflife=7,title=1", or "bm25" to rank by text relevance only`)
+	sc.output = addOutputFlags(sc.fs, "")
+	return sc.fs
+}
+
+func (sc *searchCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	query := strings.Join(sc.fs.Args(), " ")
+	if (query == "") == (*sc.query == "") {
+		sc.fs.Usage()
+		return errors.New("search requires either a full text query or -q")
+	}
+	if *sc.limit < 0 || *sc.offset < 0 {
+		return errors.New("-limit and -offset can't be negative")
+	}
+
+	opts := orm.SearchOptions{
+		Limit:   *sc.limit,
+		Offset:  *sc.offset,
+		History: *sc.history,
+	}
+	if *sc.rank != "" {
+		base, err := sc.repo.GetRanking(ctx)
+		if err != nil {
+			return err
+		}
+		rk, err := orm.ParseRanking(base, *sc.rank)
+		if err != nil {
+			return err
+		}
+		opts.Ranking = &rk
+	}
+	if !sc.output.enabled() {
+		opts = highlightMatches(opts, w)
+	}
+
+	var (
+		records []searchRecord
+		err     error
+	)
+	if *sc.query != "" {
+		records, err = sc.queryNotes(ctx, *sc.query, opts)
+	} else {
+		records, err = fullTextSearch(ctx, sc.repo, query, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errNoMatch
+	}
+
+	if sc.output.enabled() {
+		return sc.output.printSearchRecords(w, records)
+	}
+
+	return printSearchMatches(ctx, sc.repo, w, records, *sc.history)
+}
+
+// highlightMatches sets the markers around the matches in snippets printed
+// for people to read, with colors in a terminal
+func highlightMatches(opts orm.SearchOptions, w io.Writer) orm.SearchOptions {
+	opts.HighlightStart, opts.HighlightEnd = "*", "*"
+	if isTerminal(w) {
+		opts.HighlightStart, opts.HighlightEnd = ansiHighlight, ansiReset
+	}
+	return opts
+}
+
+// printSearchMatches prints search results for people to read, with the
+// matches of each note below its header
+func printSearchMatches(ctx context.Context, repo orm.Repo, w io.Writer, records []searchRecord, history bool) error {
+	for i, rec := range records {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if rec.Line == 0 {
+			// note queries without text terms have no match to show
+			fmt.Fprintf(w, "%d\t%s\t%s\n",
+				rec.ID, rec.Timestamp.Local().Format(timestampLayout), rec.Header)
+			continue
+		}
+
+		// scores are negative, the lower the better
+		fmt.Fprintf(w, "%d\t%s\t%.3g\tline %d\t%s\n",
+			rec.ID, rec.Timestamp.Local().Format(timestampLayout), rec.Score, rec.Line, rec.Header)
+		if history {
+			current, err := repo.GetCurrentNoteRev(ctx, rec.ID)
+			if err != nil {
+				return fmt.Errorf("getting current rev of note %d: %w", rec.ID, err)
+			}
+			rev := shortSHA(rec.SHA256)
+			if current.SHA256 == rec.SHA256 {
+				fmt.Fprintf(w, "\trev %s (current)\n", rev)
+			} else {
+				fmt.Fprintf(w, "\trev %s, compare with `nst view -id %d -rev %s -diff`\n", rev, rec.ID, rev)
+			}
+		}
+		for _, line := range strings.Split(strings.TrimSpace(rec.Snippet), "\n") {
+			fmt.Fprintf(w, "\t%s\n", line)
+		}
+	}
+
+	return nil
+}
+
+// fullTextSearch runs a full text search, or a saved search with a query of
+// "@name"
+func fullTextSearch(ctx context.Context, repo orm.Repo, query string, opts orm.SearchOptions) ([]searchRecord, error) {
+	query, err := repo.ResolveSearch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := repo.FullTextSearchOpts(ctx, query, opts)
+	if errors.Is(err, orm.ErrHistoryDisabled) {
+		return nil, fmt.Errorf("%w, enable it with `nst set-config -key %s -value true`", err, orm.ConfigHistoryIndex)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("full text search: %w", err)
+	}
+	if len(results) == 0 && !opts.History {
+		return nil, noMatchError(ctx, repo, query)
+	}
+
+	records := make([]searchRecord, len(results))
+	for i, result := range results {
+		if records[i], err = newSearchRecord(ctx, repo, result); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (sc *searchCmd) queryNotes(ctx context.Context, query string, opts orm.SearchOptions) ([]searchRecord, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := sc.repo.QueryNotes(ctx, q, opts)
+	if err != nil {
+		return nil, fmt.Errorf("querying notes: %w", err)
+	}
+
+	records := make([]searchRecord, len(results))
+	for i, result := range results {
+		if records[i], err = newQueryRecord(ctx, sc.repo, result); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// noMatchError is errNoMatch for a full text search query, suggesting
+// corrections of the words of the query that aren't found in any note
+func noMatchError(ctx context.Context, repo orm.Repo, query string) error {
+	suggestions, err := repo.SuggestQueries(ctx, query, 3)
+	if err != nil {
+		return fmt.Errorf("suggesting queries: %w", err)
+	}
+	if len(suggestions) == 0 {
+		return errNoMatch
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = strconv.Quote(s)
+	}
+	return fmt.Errorf("%w, did you mean %s?", errNoMatch, strings.Join(quoted, " or "))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+func newStopWordsCmd(repo orm.Repo) subCmd {
+	return &groupCmd{
+		names: []string{"stopwords", "sw"},
+		help:  `Manage the words left out of the word cloud and related notes.`,
+		actions: []subCmd{
+			&stopWordsAddCmd{repo: repo},
+			&stopWordsRemoveCmd{repo: repo},
+			&stopWordsListCmd{repo: repo},
+		},
+	}
+}
+
+type stopWordsAddCmd struct {
+	repo orm.Repo
+	fs   *flag.FlagSet
+}
+
+func (_ *stopWordsAddCmd) Help() string {
+	return `Add the words given as arguments to the stop words.`
+}
+
+func (_ *stopWordsAddCmd) Names() []string {
+	return []string{"add"}
+}
+
+func (sac *stopWordsAddCmd) FlagSet() *flag.FlagSet {
+	sac.fs = flag.NewFlagSet("stopwords add", flag.ExitOnError)
+	return sac.fs
+}
+
+func (sac *stopWordsAddCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if sac.fs.NArg() == 0 {
+		return errors.New("give the stop words to add as arguments")
+	}
+	return sac.repo.AddStopWords(ctx, sac.fs.Args()...)
+}
+
+type stopWordsRemoveCmd struct {
+	repo orm.Repo
+	fs   *flag.FlagSet
+}
+
+func (_ *stopWordsRemoveCmd) Help() string {
+	return `Remove the words given as arguments from the stop words.`
+}
+
+func (_ *stopWordsRemoveCmd) Names() []string {
+	return []string{"rm"}
+}
+
+func (src *stopWordsRemoveCmd) FlagSet() *flag.FlagSet {
+	src.fs = flag.NewFlagSet("stopwords rm", flag.ExitOnError)
+	return src.fs
+}
+
+func (src *stopWordsRemoveCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if src.fs.NArg() == 0 {
+		return errors.New("give the stop words to remove as arguments")
+	}
+	return src.repo.DeleteStopWords(ctx, src.fs.Args()...)
+}
+
+type stopWordsListCmd struct {
+	repo   orm.Repo
+	output *outputFlags
+}
+
+func (_ *stopWordsListCmd) Help() string {
+	return `List the stop words in alphabetical order.`
+}
+
+func (_ *stopWordsListCmd) Names() []string {
+	return []string{"list", "ls"}
+}
+
+func (slc *stopWordsListCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("stopwords list", flag.ExitOnError)
+	slc.output = addOutputFlags(fs, formatTable)
+	return fs
+}
+
+// stopWordRecord is the output of a stop word
+type stopWordRecord struct {
+	Word string
+}
+
+func (slc *stopWordsListCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	words, err := slc.repo.GetStopWords(ctx)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return errNoMatch
+	}
+
+	return slc.output.print(w, []string{"WORD"}, len(words), func(i int) (interface{}, []string) {
+		return stopWordRecord{Word: words[i]}, []string{words[i]}
+	})
+}
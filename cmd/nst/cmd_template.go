@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+func newTemplateCmd(repo orm.Repo) subCmd {
+	return &groupCmd{
+		names: []string{"template", "tm"},
+		help:  `Manage note templates stored in the nest.`,
+		actions: []subCmd{
+			&templateAddCmd{repo: repo},
+			&templateListCmd{repo: repo},
+			&templateEditCmd{repo: repo},
+		},
+	}
+}
+
+type templateAddCmd struct {
+	repo orm.Repo
+	name *string
+	msg  *string
+}
+
+func (_ *templateAddCmd) Help() string {
+	return `Add a template from stdin, an arg or your editor of choice.`
+}
+
+func (_ *templateAddCmd) Names() []string {
+	return []string{"add"}
+}
+
+func (tac *templateAddCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("template add", flag.ExitOnError)
+	tac.name = fs.String("name", "", "name of the new template")
+	tac.msg = fs.String("m", "", "template body, instead of reading stdin or invoking the editor")
+	return fs
+}
+
+func (tac *templateAddCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *tac.name == "" {
+		return errors.New("name the template with -name")
+	}
+
+	_, err := tac.repo.GetTemplate(ctx, *tac.name)
+	if err == nil {
+		return fmt.Errorf("template %q already exists, use `nst template edit`", *tac.name)
+	}
+	if !errors.Is(err, orm.ErrNotFound) {
+		return err
+	}
+
+	body := []byte(*tac.msg)
+	switch {
+	case *tac.msg != "":
+	case isPiped(r):
+		body, err = ioutil.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	default:
+		body, err = editTemplateBody(ctx, tac.repo, "", r, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tac.repo.SaveTemplate(ctx, orm.Template{Name: *tac.name, Body: string(body)})
+}
+
+type templateListCmd struct {
+	repo orm.Repo
+}
+
+func (_ *templateListCmd) Help() string {
+	return `List the templates in the nest.`
+}
+
+func (_ *templateListCmd) Names() []string {
+	return []string{"list", "ls"}
+}
+
+func (tlc *templateListCmd) FlagSet() *flag.FlagSet {
+	return flag.NewFlagSet("template list", flag.ExitOnError)
+}
+
+func (tlc *templateListCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	templates, err := tlc.repo.GetTemplates(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range templates {
+		head := strings.SplitN(t.Body, "\n", 2)[0]
+		fmt.Fprintf(tw, "%s\t%s\n", t.Name, head)
+	}
+	return tw.Flush()
+}
+
+type templateEditCmd struct {
+	repo orm.Repo
+	name *string
+}
+
+func (_ *templateEditCmd) Help() string {
+	return `Edit a template with your editor of choice.`
+}
+
+func (_ *templateEditCmd) Names() []string {
+	return []string{"edit"}
+}
+
+func (tec *templateEditCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("template edit", flag.ExitOnError)
+	tec.name = fs.String("name", "", "name of the template to edit")
+	return fs
+}
+
+func (tec *templateEditCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	t, err := tec.repo.GetTemplate(ctx, *tec.name)
+	if err != nil {
+		return err
+	}
+
+	body, err := editTemplateBody(ctx, tec.repo, t.Body, r, w)
+	if err != nil {
+		return err
+	}
+	t.Body = string(body)
+
+	return tec.repo.SaveTemplate(ctx, t)
+}
+
+func editTemplateBody(ctx context.Context, repo orm.Repo, body string, r io.Reader, w io.Writer) ([]byte, error) {
+	edited, err := runEditor(ctx, repo, bytes.NewBufferString(body), editorOpts{}, r, w, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("run external editor: %w", err)
+	}
+	defer edited.Close()
+
+	return ioutil.ReadAll(edited)
+}
+
+// renderNoteTemplate renders the named template, prompting on the terminal
+// for the value of each field the template asks for
+func renderNoteTemplate(ctx context.Context, repo orm.Repo, name string, r io.Reader) ([]byte, error) {
+	t, err := repo.GetTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	answers := bufio.NewReader(r)
+	data := orm.NewTemplateData()
+	data.Prompt = func(field string) (string, error) {
+		fmt.Fprintf(os.Stderr, "%s: ", field)
+		answer, err := answers.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+			return "", err
+		}
+		return strings.TrimRight(answer, "\r\n"), nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Render(buf, data); err != nil {
+		return nil, fmt.Errorf("rendering template %q: %w", name, err)
+	}
+
+	return buf.Bytes(), nil
+}
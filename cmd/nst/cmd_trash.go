@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+func newTrashCmd(repo orm.Repo) subCmd {
+	return &groupCmd{
+		names: []string{"trash"},
+		help:  `Move notes to the trash, list them and restore them.`,
+		actions: []subCmd{
+			&trashAddCmd{repo: repo},
+			&trashListCmd{repo: repo},
+			&trashRestoreCmd{repo: repo},
+		},
+	}
+}
+
+type trashAddCmd struct {
+	repo orm.Repo
+	id   *int64
+}
+
+func (_ *trashAddCmd) Help() string {
+	return `Move a note to the trash, leaving it out of listings and searches.`
+}
+
+func (_ *trashAddCmd) Names() []string {
+	return []string{"add"}
+}
+
+func (tac *trashAddCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("trash add", flag.ExitOnError)
+	tac.id = fs.Int64("id", 0, "ID of the note to trash")
+	return fs
+}
+
+func (tac *trashAddCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *tac.id == 0 {
+		return errors.New("give the ID of the note to trash with -id")
+	}
+	return tac.repo.TrashNote(ctx, *tac.id)
+}
+
+type trashListCmd struct {
+	repo   orm.Repo
+	output *outputFlags
+}
+
+func (_ *trashListCmd) Help() string {
+	return `List the trashed notes, most recently trashed first.`
+}
+
+func (_ *trashListCmd) Names() []string {
+	return []string{"list", "ls"}
+}
+
+func (tlc *trashListCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("trash list", flag.ExitOnError)
+	tlc.output = addOutputFlags(fs, formatTable)
+	return fs
+}
+
+// trashRecord is the output of a trashed note
+type trashRecord struct {
+	noteRecord
+	TrashedAt string
+}
+
+func (tlc *trashListCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	trashed, err := tlc.repo.GetTrashedNotes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(trashed) == 0 {
+		return errNoMatch
+	}
+
+	records := make([]trashRecord, len(trashed))
+	for i, tn := range trashed {
+		nr, err := newNoteRecord(ctx, tlc.repo, tn.NoteRev)
+		if err != nil {
+			return err
+		}
+		records[i] = trashRecord{noteRecord: nr, TrashedAt: tn.TrashedAt.Format(timestampLayout)}
+	}
+
+	columns := []string{"ID", "MODIFIED", "TRASHED", "HEADER"}
+	return tlc.output.print(w, columns, len(records), func(i int) (interface{}, []string) {
+		rec := records[i]
+		return rec, []string{
+			fmt.Sprint(rec.ID),
+			rec.Timestamp.Local().Format(timestampLayout),
+			rec.TrashedAt,
+			rec.Header,
+		}
+	})
+}
+
+type trashRestoreCmd struct {
+	repo orm.Repo
+	id   *int64
+}
+
+func (_ *trashRestoreCmd) Help() string {
+	return `Take a note out of the trash.`
+}
+
+func (_ *trashRestoreCmd) Names() []string {
+	return []string{"restore"}
+}
+
+func (trc *trashRestoreCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("trash restore", flag.ExitOnError)
+	trc.id = fs.Int64("id", 0, "ID of the note to restore")
+	return fs
+}
+
+func (trc *trashRestoreCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	if *trc.id == 0 {
+		return errors.New("give the ID of the note to restore with -id")
+	}
+	return trc.repo.RestoreNote(ctx, *trc.id)
+}
@@ -1,19 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/pokstad/nestable/orm"
 )
 
 type viewCmd struct {
-	repo   orm.Repo
-	search *string
+	repo    orm.Repo
+	noteID  *int64
+	search  *string
+	rev     *string
+	diff    *bool
+	related *int
+	output  *outputFlags
 }
 
 func newViewCmd(repo orm.Repo) subCmd {
@@ -30,24 +37,53 @@ func (_ *viewCmd) Names() []string {
 
 func (vc *viewCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("view", flag.ExitOnError)
-	vc.search = fs.String("s", "", "full text search term to filter results")
+	vc.noteID = fs.Int64("id", 0, "note ID you want to view")
+	vc.search = fs.String("s", "", "full text search term, or @name of a saved search, to filter results")
+	vc.rev = fs.String("rev", "", "view the revision of the note whose SHA256 starts with this prefix")
+	vc.diff = fs.Bool("diff", false, "print the changes from the revision to the current revision, or from the previous revision")
+	vc.related = fs.Int("related", 5, "number of related notes listed below the note, 0 for none")
+	vc.output = addOutputFlags(fs, "")
 	return fs
 }
 
 func (vc *viewCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 	var rev orm.NoteRev
 
-	if *vc.search != "" {
-		results, err := vc.repo.FullTextSearch(ctx, *vc.search)
+	if *vc.noteID != 0 {
+		var err error
+		rev, err = vc.repo.GetCurrentNoteRev(ctx, *vc.noteID)
+		if err != nil {
+			return fmt.Errorf("getting current note rev for ID %d: %w", *vc.noteID, err)
+		}
+	}
+
+	if *vc.search != "" && rev == (orm.NoteRev{}) {
+		query, err := vc.repo.ResolveSearch(ctx, *vc.search)
+		if err != nil {
+			return err
+		}
+
+		results, err := vc.repo.FullTextSearch(ctx, query)
 		if err != nil {
 			return fmt.Errorf("full text search with term %q: %w", *vc.search, err)
 		}
+		if len(results) == 0 {
+			return noMatchError(ctx, vc.repo, query)
+		}
 
-		rev, err = selectFTSResults(ctx, vc.repo, results)
+		if vc.output.enabled() {
+			return vc.output.printSearchResults(ctx, vc.repo, w, results)
+		}
+
+		result, err := selectFTSResults(ctx, vc.repo, results)
 		if err != nil {
 			return fmt.Errorf("selecting search results: %w", err)
 		}
 
+		rev, err = result.GetNoteRev(ctx, vc.repo)
+		if err != nil {
+			return fmt.Errorf("fetch note rev for search result: %w", err)
+		}
 	}
 
 	if rev == (orm.NoteRev{}) {
@@ -58,6 +94,18 @@ func (vc *viewCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 		}
 	}
 
+	if *vc.rev != "" {
+		var err error
+		rev, err = vc.repo.FindNoteRev(ctx, rev.ID, *vc.rev)
+		if err != nil {
+			return fmt.Errorf("finding revision to view: %w", err)
+		}
+	}
+
+	if *vc.diff {
+		return printNoteDiff(ctx, vc.repo, w, rev)
+	}
+
 	bReader, err := rev.GetReader(ctx, vc.repo)
 	if err != nil {
 		return fmt.Errorf("getting blob reader: %w", err)
@@ -68,6 +116,21 @@ func (vc *viewCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 		return fmt.Errorf("reading blob: %w", err)
 	}
 
+	if vc.output.enabled() {
+		rec := bodyRecord{NoteRev: rev, Body: string(raw)}
+		return vc.output.print(w, []string{"ID", "MODIFIED", "BODY"}, 1, func(int) (interface{}, []string) {
+			return rec, []string{fmt.Sprint(rev.ID), rev.Timestamp.Local().Format(timestampLayout), rec.Body}
+		})
+	}
+
+	if *vc.related > 0 {
+		section, err := relatedSection(ctx, vc.repo, rev.ID, *vc.related)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, section...)
+	}
+
 	out, err := glamour.RenderBytes(raw, "ascii")
 	if err != nil {
 		return fmt.Errorf("rendering blob: %w", err)
@@ -77,3 +140,26 @@ func (vc *viewCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
 
 	return nil
 }
+
+// relatedSection lists the notes related to a note as a Markdown section, or
+// nothing when no note is related
+func relatedSection(ctx context.Context, repo orm.Repo, id int64, n int) ([]byte, error) {
+	related, err := repo.RelatedNotes(ctx, id, n)
+	if err != nil {
+		return nil, fmt.Errorf("finding notes related to note %d: %w", id, err)
+	}
+	if len(related) == 0 {
+		return nil, nil
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("\n\n---\n\n## Related\n\n")
+	for _, rn := range related {
+		rec, err := newNoteRecord(ctx, repo, rn.NoteRev)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(b, "- [%d] %s (%.0f%% similar)\n", rn.ID, strings.TrimLeft(rec.Header, "# "), rn.Similarity*100)
+	}
+	return b.Bytes(), nil
+}
@@ -4,14 +4,19 @@ import (
 	"context"
 	"flag"
 	"io"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pokstad/nestable/internal/web"
 	"github.com/pokstad/nestable/orm"
 )
 
 type webCmd struct {
-	repo   orm.Repo
-	search *string
+	repo        orm.Repo
+	addr        *string
+	open        *bool
+	logRequests *bool
 }
 
 func newWebCmd(repo orm.Repo) subCmd {
@@ -28,9 +33,21 @@ func (_ *webCmd) Names() []string {
 
 func (vc *webCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	vc.addr = fs.String("addr", web.DefaultAddr, `address to listen on, e.g. ":3000" for all interfaces`)
+	vc.open = fs.Bool("open", false, "open the web UI in the default browser")
+	vc.logRequests = fs.Bool("log", false, "log each request to stderr")
 	return fs
 }
 
 func (vc *webCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
-	return web.Serve(ctx, vc.repo, r, w)
+	// shut down gracefully on Ctrl-C or when terminated
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := web.Options{Addr: *vc.addr, OpenBrowser: *vc.open}
+	if *vc.logRequests {
+		opts.Log = os.Stderr
+	}
+
+	return web.Serve(ctx, vc.repo, opts)
 }
@@ -2,18 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"sort"
 
 	"github.com/charmbracelet/glamour"
 	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/pokstad/nestable/internal/wordcloud"
 	"github.com/pokstad/nestable/orm"
 )
 
 type wordCloudCmd struct {
-	repo orm.Repo
+	repo     orm.Repo
+	since    *string
+	between  *string
+	trending *bool
+	phrases  *bool
+	svg      *string
+	output   *outputFlags
 }
 
 func newWorkCloudCmd(repo orm.Repo) subCmd {
@@ -30,25 +40,136 @@ func (_ *wordCloudCmd) Names() []string {
 
 func (wcc *wordCloudCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("wordCloud", flag.ExitOnError)
+	wcc.since = fs.String("since", "", `only count notes modified since a duration ago, e.g. "36h", "7d" or "2w", or since a day, e.g. "2026-01-01"`)
+	wcc.between = fs.String("between", "", `only count notes modified between two days, e.g. "2026-01-01..2026-01-31"`)
+	wcc.trending = fs.Bool("trending", false, "show the terms more frequent in the notes of -since or -between than in all notes")
+	wcc.phrases = fs.Bool("phrases", false, `include frequent phrases of two or three words, e.g. "load balancer"`)
+	wcc.svg = fs.String("svg", "", `write the word cloud as an SVG image to a file, or to stdout with "-"`)
+	wcc.output = addOutputFlags(fs, "")
 	return fs
 }
 
 func (wcc *wordCloudCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
-	terms, err := wcc.repo.WordCloudTerms(ctx)
+	window, err := orm.ParseTimeWindow(*wcc.since, *wcc.between)
+	if err != nil {
+		return err
+	}
+
+	if *wcc.trending {
+		if *wcc.phrases {
+			return errors.New("-phrases can't be combined with -trending")
+		}
+		return wcc.runTrending(ctx, window, w)
+	}
+
+	terms, err := wcc.repo.WordCloudTermsIn(ctx, window)
 	if err != nil {
 		return fmt.Errorf("fetching word cloud: %w", err)
 	}
+	if *wcc.phrases {
+		phrases, err := wcc.repo.WordCloudPhrases(ctx, window)
+		if err != nil {
+			return fmt.Errorf("fetching word cloud phrases: %w", err)
+		}
+		terms = append(terms, phrases...)
+		sort.SliceStable(terms, func(i, j int) bool { return terms[i].InstanceCount > terms[j].InstanceCount })
+	}
+	if len(terms) == 0 {
+		return errNoMatch
+	}
 
-	idx, err := fuzzyfinder.Find(terms, func(i int) string {
+	if *wcc.svg != "" {
+		return writeWordCloudSVG(*wcc.svg, terms, w)
+	}
+
+	if wcc.output.enabled() {
+		return wcc.output.print(w, []string{"TERM", "INSTANCES", "NOTES"}, len(terms), func(i int) (interface{}, []string) {
+			t := terms[i]
+			return t, []string{t.Term, fmt.Sprint(t.InstanceCount), fmt.Sprint(t.NoteCount)}
+		})
+	}
+
+	return wcc.selectTerm(ctx, terms, window, func(i int) string {
 		return fmt.Sprintf("%20s - appears %5d in %5d notes",
 			terms[i].Term, terms[i].InstanceCount, terms[i].NoteCount)
-	})
+	}, w)
+}
+
+func (wcc *wordCloudCmd) runTrending(ctx context.Context, window orm.TimeWindow, w io.Writer) error {
+	if window.IsZero() {
+		return errors.New("trending terms require a time window, set -since or -between")
+	}
+
+	trending, err := wcc.repo.TrendingTerms(ctx, window)
+	if err != nil {
+		return fmt.Errorf("fetching trending terms: %w", err)
+	}
+	if len(trending) == 0 {
+		return errNoMatch
+	}
+
+	terms := make([]orm.WCTerm, len(trending))
+	for i, t := range trending {
+		terms[i] = t.WCTerm
+	}
+
+	if *wcc.svg != "" {
+		return writeWordCloudSVG(*wcc.svg, terms, w)
+	}
+
+	if wcc.output.enabled() {
+		columns := []string{"TERM", "INSTANCES", "NOTES", "BASELINE", "LIFT"}
+		return wcc.output.print(w, columns, len(trending), func(i int) (interface{}, []string) {
+			t := trending[i]
+			return t, []string{
+				t.Term,
+				fmt.Sprint(t.InstanceCount),
+				fmt.Sprint(t.NoteCount),
+				fmt.Sprint(t.BaselineCount),
+				fmt.Sprintf("%.2f", t.Lift),
+			}
+		})
+	}
+
+	return wcc.selectTerm(ctx, terms, window, func(i int) string {
+		return fmt.Sprintf("%20s - %5.1fx as frequent, appears %5d in %5d notes",
+			trending[i].Term, trending[i].Lift, trending[i].InstanceCount, trending[i].NoteCount)
+	}, w)
+}
+
+// writeWordCloudSVG draws the terms as a word cloud in an SVG image written
+// to the file at path, or to w when path is "-"
+func writeWordCloudSVG(path string, terms []orm.WCTerm, w io.Writer) error {
+	words := make([]wordcloud.Word, len(terms))
+	for i, t := range terms {
+		words[i] = wordcloud.Word{Text: t.Term, Weight: t.InstanceCount}
+	}
+
+	if path == "-" {
+		return wordcloud.WriteSVG(w, words, wordcloud.Options{})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating word cloud image: %w", err)
+	}
+	if err := wordcloud.WriteSVG(f, words, wordcloud.Options{}); err != nil {
+		f.Close()
+		return fmt.Errorf("writing word cloud image: %w", err)
+	}
+	return f.Close()
+}
+
+// selectTerm lets the user pick a term, then a note modified within the
+// window it appears in, and renders the note
+func (wcc *wordCloudCmd) selectTerm(ctx context.Context, terms []orm.WCTerm, window orm.TimeWindow, label func(i int) string, w io.Writer) error {
+	idx, err := fuzzyfinder.Find(terms, label)
 	if err != nil {
 		return fmt.Errorf("selecting word cloud term: %w", err)
 	}
 
 	selectedTerm := terms[idx]
-	termInstances, err := selectedTerm.Instances(ctx, wcc.repo)
+	termInstances, err := selectedTerm.InstancesIn(ctx, wcc.repo, window)
 	if err != nil {
 		return fmt.Errorf("fetching instances for %q: %w",
 			selectedTerm.Term, err)
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/pokstad/nestable/orm"
+)
+
+// ANSI escape codes to color diff lines in a terminal
+const (
+	ansiRemoved = "\x1b[31m"
+	ansiAdded   = "\x1b[32m"
+	ansiHunk    = "\x1b[36m"
+)
+
+// printNoteDiff writes a unified diff of a revision against the current
+// revision of the note. When rev is the current revision, the previous
+// revision is compared instead.
+func printNoteDiff(ctx context.Context, repo orm.Repo, w io.Writer, rev orm.NoteRev) error {
+	current, err := repo.GetCurrentNoteRev(ctx, rev.ID)
+	if err != nil {
+		return fmt.Errorf("getting current rev of note %d: %w", rev.ID, err)
+	}
+
+	if rev == current {
+		revs, err := repo.GetNoteRevs(ctx, rev.ID)
+		if err != nil {
+			return fmt.Errorf("getting revs of note %d: %w", rev.ID, err)
+		}
+		if len(revs) < 2 {
+			return fmt.Errorf("note %d has a single revision", rev.ID)
+		}
+		rev = revs[len(revs)-2]
+	}
+
+	from, err := readRevBody(ctx, repo, rev)
+	if err != nil {
+		return err
+	}
+	to, err := readRevBody(ctx, repo, current)
+	if err != nil {
+		return err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fmt.Sprintf("note %d rev %s", rev.ID, shortSHA(rev.SHA256)),
+		FromDate: rev.Timestamp.Local().Format(timestampLayout),
+		ToFile:   fmt.Sprintf("note %d rev %s (current)", current.ID, shortSHA(current.SHA256)),
+		ToDate:   current.Timestamp.Local().Format(timestampLayout),
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("diffing note %d: %w", rev.ID, err)
+	}
+
+	if !isTerminal(w) {
+		_, err = io.WriteString(w, diff)
+		return err
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			color = ansiAdded
+		case strings.HasPrefix(line, "-"):
+			color = ansiRemoved
+		case strings.HasPrefix(line, "@@"):
+			color = ansiHunk
+		}
+		if color != "" {
+			line = color + strings.TrimSuffix(line, "\n") + ansiReset + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readRevBody(ctx context.Context, repo orm.Repo, rev orm.NoteRev) (string, error) {
+	bReader, err := rev.GetReader(ctx, repo)
+	if err != nil {
+		return "", fmt.Errorf("getting blob reader: %w", err)
+	}
+
+	raw, err := ioutil.ReadAll(bReader)
+	if err != nil {
+		return "", fmt.Errorf("reading blob: %w", err)
+	}
+
+	return string(raw), nil
+}
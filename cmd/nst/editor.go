@@ -2,34 +2,212 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/pokstad/nestable/orm"
 )
 
-var editorOptions = map[string][]string{
-	"mate": []string{"--wait"},
+const (
+	// editorFileArg is replaced with the path of the note being edited
+	editorFileArg = "{file}"
+	// editorLineArg is replaced with the line the cursor should start on
+	editorLineArg = "{line}"
+)
+
+// editorTemplates are argument templates for well known editors. They
+// make the editor wait until the file is closed and open it at a line.
+var editorTemplates = map[string]string{
+	"mate":  "--wait --line {line} {file}",
+	"code":  "--wait --goto {file}:{line}",
+	"subl":  "--wait {file}:{line}",
+	"vi":    "+{line} {file}",
+	"vim":   "+{line} {file}",
+	"nvim":  "+{line} {file}",
+	"nano":  "+{line} {file}",
+	"emacs": "+{line} {file}",
+	"kak":   "+{line} {file}",
+	"hx":    "{file}:{line}",
+}
+
+// editorOpts tune how a note is presented in the editor
+type editorOpts struct {
+	// line to place the cursor on, starting at 1. Zero means the top.
+	line int
+	// ext is the temp file extension. Empty defers to the editor_ext config.
+	ext string
+	// onSave is called with the note each time the external editor saves it
+	onSave func([]byte) error
+}
+
+// defaultEditor is the editor config value nests are created with
+const defaultEditor = "vi"
+
+// editorCommand resolves the editor command line from the nest config.
+// An empty or default config falls back to the VISUAL and EDITOR
+// environment variables, and then to the builtin editor or the default.
+func editorCommand(ctx context.Context, repo orm.Repo) (string, error) {
+	editor, err := repo.GetConfig(ctx, orm.ConfigEditor)
+	if err != nil && !errors.Is(err, orm.ErrNotFound) {
+		return "", fmt.Errorf("getting editor config: %w", err)
+	}
+
+	return resolveEditor(editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")), nil
+}
+
+// resolveEditor picks the editor from its config and environment variables
+func resolveEditor(config, visual, editor string) string {
+	fallback := builtinEditor
+	switch strings.TrimSpace(config) {
+	case "":
+	case defaultEditor:
+		// the default runs only when the environment has no editor
+		fallback = config
+	default:
+		return config
+	}
+
+	for _, e := range []string{visual, editor} {
+		if strings.TrimSpace(e) != "" {
+			return e
+		}
+	}
+
+	return fallback
+}
+
+// editorArgs expands an editor command line into the program and its
+// arguments. Commands without a {file} placeholder use the template for
+// well known editors, or have the file appended otherwise.
+func editorArgs(editor, file string, line int) (string, []string, error) {
+	args, err := splitArgs(editor)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing editor command %q: %w", editor, err)
+	}
+	if len(args) == 0 {
+		return "", nil, errors.New("empty editor command")
+	}
+
+	if !strings.Contains(editor, editorFileArg) {
+		if tmpl, ok := editorTemplates[filepath.Base(args[0])]; ok && len(args) == 1 {
+			tmplArgs, err := splitArgs(tmpl)
+			if err != nil {
+				return "", nil, fmt.Errorf("parsing editor template %q: %w", tmpl, err)
+			}
+			args = append(args, tmplArgs...)
+		} else {
+			args = append(args, editorFileArg)
+		}
+	}
+
+	if line < 1 {
+		line = 1
+	}
+
+	for i, a := range args {
+		a = strings.ReplaceAll(a, editorFileArg, file)
+		args[i] = strings.ReplaceAll(a, editorLineArg, strconv.Itoa(line))
+	}
+
+	return args[0], args[1:], nil
 }
 
-func runEditor(ctx context.Context, repo orm.Repo, blob, stdin io.Reader, stdout, stderr io.Writer) (io.ReadCloser, error) {
-	editor, err := repo.GetConfig(ctx, "editor")
+// splitArgs splits a command line into arguments the way a shell would
+// for whitespace, quotes and backslash escapes
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, c := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+
+	return args, nil
+}
+
+func runEditor(ctx context.Context, repo orm.Repo, blob io.Reader, opts editorOpts, stdin io.Reader, stdout, stderr io.Writer) (io.ReadCloser, error) {
+	editor, err := editorCommand(ctx, repo)
 	if err != nil {
-		return nil, fmt.Errorf("getting editor config: %w", err)
+		return nil, err
 	}
 
-	edOpts := editorOptions[editor]
+	if strings.TrimSpace(editor) == builtinEditor {
+		return runBuiltinEditor(blob, opts, stdin, stdout)
+	}
 
-	tf, err := ioutil.TempFile("", "nestable-*.md")
+	ext := opts.ext
+	if ext == "" {
+		ext, err = repo.GetConfig(ctx, orm.ConfigEditorExt)
+		if err != nil {
+			return nil, fmt.Errorf("getting editor extension config: %w", err)
+		}
+	}
+	ext = strings.TrimPrefix(ext, ".")
+
+	pattern := "nestable-*"
+	if ext != "" {
+		pattern += "." + ext
+	}
+
+	tf, err := ioutil.TempFile("", pattern)
 	if err != nil {
 		return nil, fmt.Errorf("temp file open: %w", err)
 	}
 
-	_, err = io.Copy(tf, blob)
+	initial, err := ioutil.ReadAll(blob)
 	if err != nil {
+		return nil, fmt.Errorf("reading blob: %w", err)
+	}
+
+	if _, err := tf.Write(initial); err != nil {
 		return nil, fmt.Errorf("copying blob to temp file: %w", err)
 	}
 
@@ -37,13 +215,35 @@ func runEditor(ctx context.Context, repo orm.Repo, blob, stdin io.Reader, stdout
 		return nil, fmt.Errorf("closing temp file: %w", err)
 	}
 
-	edOpts = append(edOpts, tf.Name())
+	name, args, err := editorArgs(editor, tf.Name(), opts.line)
+	if err != nil {
+		return nil, err
+	}
 
-	cmd := exec.Command(editor, edOpts...)
+	cmd := exec.Command(name, args...)
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	if err = cmd.Run(); err != nil {
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	if opts.onSave != nil {
+		fi, err := os.Stat(tf.Name())
+		if err != nil {
+			return nil, fmt.Errorf("stat temp file: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watchFile(tf.Name(), fi.ModTime(), initial, opts.onSave, stop, stderr)
+		}()
+	}
+
+	err = cmd.Run()
+	close(stop)
+	wg.Wait()
+	if err != nil {
 		return nil, fmt.Errorf("running editor: %w", err)
 	}
 
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	for _, tc := range []struct {
+		cmd  string
+		args []string
+		err  string
+	}{
+		{cmd: "vim", args: []string{"vim"}},
+		{cmd: "  code\t--wait \n", args: []string{"code", "--wait"}},
+		{cmd: `emacs -nw "my notes"`, args: []string{"emacs", "-nw", "my notes"}},
+		{cmd: `ed 'it''s'`, args: []string{"ed", "its"}},
+		{cmd: `ed 'a\b' "c\"d"`, args: []string{"ed", `a\b`, `c"d`}},
+		{cmd: `ed a\ b \'c\'`, args: []string{"ed", "a b", "'c'"}},
+		{cmd: `ed ""`, args: []string{"ed", ""}},
+		{cmd: "", args: nil},
+		{cmd: `ed "open`, err: `unterminated " quote`},
+		{cmd: `ed 'open`, err: "unterminated ' quote"},
+		{cmd: `ed \`, err: "trailing backslash"},
+	} {
+		t.Run(tc.cmd, func(t *testing.T) {
+			args, err := splitArgs(tc.cmd)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.args, args)
+		})
+	}
+}
+
+func TestEditorArgs(t *testing.T) {
+	for _, tc := range []struct {
+		editor string
+		line   int
+		name   string
+		args   []string
+	}{
+		// well known editors get their template
+		{editor: "vim", line: 12, name: "vim", args: []string{"+12", "/tmp/note.md"}},
+		{editor: "/usr/local/bin/code", line: 3, name: "/usr/local/bin/code", args: []string{"--wait", "--goto", "/tmp/note.md:3"}},
+		// a line with no search hit starts at the top
+		{editor: "vim", line: 0, name: "vim", args: []string{"+1", "/tmp/note.md"}},
+		{editor: "hx {file}:{line}", line: -1, name: "hx", args: []string{"/tmp/note.md:1"}},
+		// commands without {file} have the file appended
+		{editor: "vim -u NONE", line: 5, name: "vim", args: []string{"-u", "NONE", "/tmp/note.md"}},
+		{editor: "ed", line: 5, name: "ed", args: []string{"/tmp/note.md"}},
+		{editor: `"my editor" --line={line}`, line: 7, name: "my editor", args: []string{"--line=7", "/tmp/note.md"}},
+		// templates place both placeholders anywhere
+		{editor: "code --wait --goto {file}:{line}", line: 9, name: "code", args: []string{"--wait", "--goto", "/tmp/note.md:9"}},
+		{editor: "vim +{line} {file} +{line}", line: 2, name: "vim", args: []string{"+2", "/tmp/note.md", "+2"}},
+	} {
+		t.Run(tc.editor, func(t *testing.T) {
+			name, args, err := editorArgs(tc.editor, "/tmp/note.md", tc.line)
+			require.NoError(t, err)
+			require.Equal(t, tc.name, name)
+			require.Equal(t, tc.args, args)
+		})
+	}
+
+	_, _, err := editorArgs("  ", "/tmp/note.md", 1)
+	require.EqualError(t, err, "empty editor command")
+
+	_, _, err = editorArgs(`vim "{file}`, "/tmp/note.md", 1)
+	require.EqualError(t, err, `parsing editor command "vim \"{file}": unterminated " quote`)
+}
+
+func TestResolveEditor(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, visual, editor string
+		want                         string
+	}{
+		{name: "config", config: "emacs", visual: "code", editor: "nano", want: "emacs"},
+		{name: "default prefers VISUAL", config: "vi", visual: "code", editor: "nano", want: "code"},
+		{name: "default prefers EDITOR", config: "vi", editor: "nano", want: "nano"},
+		{name: "default without environment", config: "vi", want: "vi"},
+		{name: "empty prefers VISUAL", visual: "code", editor: "nano", want: "code"},
+		{name: "empty prefers EDITOR", visual: " ", editor: "nano", want: "nano"},
+		{name: "empty without environment", want: builtinEditor},
+		{name: "builtin", config: builtinEditor, visual: "code", want: builtinEditor},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, resolveEditor(tc.config, tc.visual, tc.editor))
+		})
+	}
+}
@@ -62,7 +62,7 @@ func selectNoteRev(ctx context.Context, repo orm.Repo, header string) (orm.NoteR
 	return notes[idx], nil
 }
 
-func selectFTSResults(ctx context.Context, repo orm.Repo, results []orm.FTSResult) (orm.NoteRev, error) {
+func selectFTSResults(ctx context.Context, repo orm.Repo, results []orm.FTSResult) (orm.FTSResult, error) {
 	idx, err := fuzzyfinder.Find(results,
 		func(i int) string {
 			return results[i].Snippet
@@ -92,13 +92,8 @@ func selectFTSResults(ctx context.Context, repo orm.Repo, results []orm.FTSResul
 	)
 
 	if err != nil {
-		return orm.NoteRev{}, fmt.Errorf("fuzzy finding search results: %w", err)
+		return orm.FTSResult{}, fmt.Errorf("fuzzy finding search results: %w", err)
 	}
 
-	nr, err := results[idx].GetNoteRev(ctx, repo)
-	if err != nil {
-		return orm.NoteRev{}, fmt.Errorf("fetch note rev for search result: %w", err)
-	}
-
-	return nr, nil
+	return results[idx], nil
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// groupCmd is a subcommand made of actions selected by the first argument,
+// e.g. `nst template add`. Each action is a subcommand with its own flags.
+type groupCmd struct {
+	names   []string
+	help    string
+	actions []subCmd
+	fs      *flag.FlagSet
+}
+
+func (gc *groupCmd) Help() string {
+	var names []string
+	for _, a := range gc.actions {
+		names = append(names, a.Names()[0])
+	}
+	return fmt.Sprintf("%s (%s)", gc.help, strings.Join(names, ", "))
+}
+
+func (gc *groupCmd) Names() []string {
+	return gc.names
+}
+
+func (gc *groupCmd) FlagSet() *flag.FlagSet {
+	gc.fs = flag.NewFlagSet(gc.names[0], flag.ExitOnError)
+	gc.fs.Usage = func() {
+		out := gc.fs.Output()
+		fmt.Fprintf(out, "Usage: nst %s <action> [options]\n\n", gc.names[0])
+		for _, a := range gc.actions {
+			fmt.Fprintf(out, "%20s\t%s\n", strings.Join(a.Names(), " or "), a.Help())
+		}
+	}
+	return gc.fs
+}
+
+func (gc *groupCmd) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	args := gc.fs.Args()
+	if len(args) == 0 {
+		gc.fs.Usage()
+		return fmt.Errorf("%s requires an action", gc.names[0])
+	}
+
+	for _, a := range gc.actions {
+		for _, n := range a.Names() {
+			if n != args[0] {
+				continue
+			}
+
+			if err := a.FlagSet().Parse(args[1:]); err != nil {
+				return err
+			}
+			return a.Run(ctx, r, w)
+		}
+	}
+
+	gc.fs.Usage()
+	return fmt.Errorf("unknown %s action: %q", gc.names[0], args[0])
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -17,6 +18,12 @@ const (
 	timestampLayout = "2006-01-02 15:04:05"
 )
 
+// exit codes are consistent across subcommands for use in scripts
+const (
+	exitNoMatch = 1 // the command found nothing to output
+	exitError   = 2 // the command failed
+)
+
 var (
 	// common flags for all subcommands
 	nestPath = flag.String("nest", "", "path to nest file")
@@ -37,12 +44,23 @@ type cmdFactory func(repo orm.Repo) subCmd
 var subCmdFactories = []cmdFactory{
 	newInitCmd,
 	newNewCmd,
+	newAppendCmd,
+	newListCmd,
+	newSearchCmd,
+	newSavedCmd,
+	newGrepCmd,
+	newTodayCmd,
+	newJournalCmd,
+	newTemplateCmd,
 	newEditCmd,
 	newViewCmd,
+	newDupesCmd,
+	newTrashCmd,
 	newBrowseCmd,
 	newGetConfigCmd,
 	newSetConfigCmd,
 	newWorkCloudCmd,
+	newStopWordsCmd,
 	newExportCmd,
 	newWebCmd,
 }
@@ -73,7 +91,8 @@ func main() {
 
 	repo, err := orm.LoadRepo(*nestPath)
 	if err != nil {
-		log.Fatalf("unable to load nest: %s", err)
+		log.Printf("unable to load nest: %s", err)
+		os.Exit(exitError)
 	}
 
 	subArgStart := len(os.Args) - flag.NArg()
@@ -88,17 +107,26 @@ func main() {
 
 	scf, ok := subCmdsLookup[sub]
 	if !ok {
-		log.Fatalf("unknown command: %q", sub)
+		log.Printf("unknown command: %q", sub)
+		os.Exit(exitError)
 	}
 
 	sc := scf(repo) // create subcommand with
 
 	if err := sc.FlagSet().Parse(subArgs); err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(exitError)
 	}
 
-	if err := sc.Run(context.Background(), os.Stdin, os.Stdout); err != nil {
-		log.Fatal(err.Error())
+	err = sc.Run(context.Background(), os.Stdin, os.Stdout)
+	switch {
+	case err == nil:
+	case errors.Is(err, errNoMatch), errors.Is(err, orm.ErrNotFound):
+		log.Print(err.Error())
+		os.Exit(exitNoMatch)
+	default:
+		log.Print(err.Error())
+		os.Exit(exitError)
 	}
 
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// errNoMatch is returned by commands that found nothing to output. The
+// process exits with exitNoMatch so scripts can tell it apart from errors.
+var errNoMatch = errors.New("no matches")
+
+// outputFlags select a non-interactive output format for scripts
+type outputFlags struct {
+	format *string
+	json   *bool
+}
+
+func addOutputFlags(fs *flag.FlagSet, defaultFormat string) *outputFlags {
+	return &outputFlags{
+		format: fs.String("format", defaultFormat, `print results non-interactively as "table", "json" (one object per line) or a Go template, e.g. '{{.ID}}'`),
+		json:   fs.Bool("json", false, "print results as JSON lines, shorthand for -format json"),
+	}
+}
+
+// enabled reports whether the results should be printed rather than
+// selected interactively
+func (of *outputFlags) enabled() bool {
+	return *of.json || *of.format != ""
+}
+
+func (of *outputFlags) formatName() string {
+	if *of.json {
+		return formatJSON
+	}
+	return *of.format
+}
+
+// print writes n records in the selected format. The record function returns
+// the value to encode as JSON or execute the template with, and the cells
+// of its table row.
+func (of *outputFlags) print(w io.Writer, columns []string, n int, record func(i int) (interface{}, []string)) error {
+	switch format := of.formatName(); format {
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		for i := 0; i < n; i++ {
+			_, cells := record(i)
+			for j, c := range cells {
+				// keep each record on a single row
+				cells[j] = strings.Join(strings.Fields(c), " ")
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		for i := 0; i < n; i++ {
+			v, _ := record(i)
+			if err := enc.Encode(v); err != nil {
+				return fmt.Errorf("encoding record: %w", err)
+			}
+		}
+		return nil
+
+	default:
+		tmpl, err := template.New("format").Parse(format)
+		if err != nil {
+			return fmt.Errorf("parsing -format template: %w", err)
+		}
+		for i := 0; i < n; i++ {
+			v, _ := record(i)
+			if err := tmpl.Execute(w, v); err != nil {
+				return fmt.Errorf("executing -format template: %w", err)
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+}
+
+// shortSHA abbreviates a blob SHA256 to identify a revision of a note
+func shortSHA(sum string) string {
+	if len(sum) > 8 {
+		return sum[:8]
+	}
+	return sum
+}
+
+// noteRecord is the output of a note revision
+type noteRecord struct {
+	orm.NoteRev
+	Header string
+}
+
+func newNoteRecord(ctx context.Context, repo orm.Repo, nr orm.NoteRev) (noteRecord, error) {
+	head, err := nr.GetBlobHead(ctx, repo, 80)
+	if err != nil {
+		return noteRecord{}, fmt.Errorf("getting header of note %d: %w", nr.ID, err)
+	}
+	return noteRecord{NoteRev: nr, Header: string(head)}, nil
+}
+
+func (nr noteRecord) cells() []string {
+	return []string{
+		fmt.Sprint(nr.ID),
+		nr.Timestamp.Local().Format(timestampLayout),
+		nr.Header,
+	}
+}
+
+var noteColumns = []string{"ID", "MODIFIED", "HEADER"}
+
+// searchRecord is the output of a full text search result
+type searchRecord struct {
+	noteRecord
+	BM25    float32
+	Score   float32
+	Line    int
+	Snippet string
+}
+
+func newSearchRecord(ctx context.Context, repo orm.Repo, result orm.FTSResult) (searchRecord, error) {
+	nr, err := result.GetNoteRev(ctx, repo)
+	if err != nil {
+		return searchRecord{}, fmt.Errorf("fetch note rev for search result: %w", err)
+	}
+
+	rec, err := newNoteRecord(ctx, repo, nr)
+	if err != nil {
+		return searchRecord{}, err
+	}
+
+	return searchRecord{
+		noteRecord: rec,
+		BM25:       result.BM25,
+		Score:      result.Score,
+		Line:       result.Line,
+		Snippet:    result.Snippet,
+	}, nil
+}
+
+func (sr searchRecord) cells() []string {
+	return append(sr.noteRecord.cells(), fmt.Sprintf("%.3g", sr.Score), fmt.Sprint(sr.Line), sr.Snippet)
+}
+
+var searchColumns = []string{"ID", "MODIFIED", "HEADER", "SCORE", "LINE", "SNIPPET"}
+
+// newQueryRecord converts a note query result with text terms to the output
+// of a search result
+func newQueryRecord(ctx context.Context, repo orm.Repo, result orm.QueryResult) (searchRecord, error) {
+	rec, err := newNoteRecord(ctx, repo, result.NoteRev)
+	if err != nil {
+		return searchRecord{}, err
+	}
+
+	return searchRecord{
+		noteRecord: rec,
+		BM25:       result.BM25,
+		Score:      result.Score,
+		Line:       result.Line,
+		Snippet:    result.Snippet,
+	}, nil
+}
+
+// printSearchResults prints full text search results non-interactively
+func (of *outputFlags) printSearchResults(ctx context.Context, repo orm.Repo, w io.Writer, results []orm.FTSResult) error {
+	records := make([]searchRecord, len(results))
+	for i, r := range results {
+		rec, err := newSearchRecord(ctx, repo, r)
+		if err != nil {
+			return err
+		}
+		records[i] = rec
+	}
+
+	return of.printSearchRecords(w, records)
+}
+
+func (of *outputFlags) printSearchRecords(w io.Writer, records []searchRecord) error {
+	return of.print(w, searchColumns, len(records), func(i int) (interface{}, []string) {
+		return records[i], records[i].cells()
+	})
+}
+
+const noteQueryUsage = `note query filtering by text, id, created, modified, revs and length, e.g. 'k8s modified:>2026-01-01 sort:-modified'`
+
+// parseQuery parses a note query and points at the syntax errors
+func parseQuery(query string) (orm.Query, error) {
+	q, err := orm.ParseQuery(query)
+	var qe orm.QueryError
+	if errors.As(err, &qe) {
+		return orm.Query{}, fmt.Errorf("%w\n%s", err, qe.Pointer())
+	}
+	return q, err
+}
+
+// bodyRecord is the output of a note revision with its full body
+type bodyRecord struct {
+	orm.NoteRev
+	Body string
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// wrappedRow is a row of a soft wrapped line. It shows the runes of the
+// line from start to end after an indent.
+type wrappedRow struct {
+	indent     string
+	start, end int
+}
+
+// markdownPrefix matches the leading markup of a line: its indent, block
+// quote markers and list item marker
+var markdownPrefix = regexp.MustCompile(`^([ \t]*(?:>[ \t]?)*)((?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?)?`)
+
+// hangingIndent returns the indent of the rows continuing a line, so that
+// list items and quotes keep their structure when they are wrapped. Quote
+// markers are repeated and list markers are replaced by spaces.
+func hangingIndent(line string) string {
+	m := markdownPrefix.FindStringSubmatch(line)
+	return m[1] + strings.Repeat(" ", runewidth.StringWidth(m[2]))
+}
+
+// isFence reports whether a line opens or closes a fenced code block
+func isFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) < 4 &&
+		(strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"))
+}
+
+// softWrap wraps a line of markdown to rows of the width. Rows break after
+// spaces, or within words longer than a row. Rows continuing a list item or
+// a quote are indented by its markup. Lines of code break at the width
+// without indent, since their spaces are significant.
+func softWrap(line string, width int, code bool) []wrappedRow {
+	runes := []rune(line)
+	if width < 1 {
+		width = 1
+	}
+
+	var indent string
+	if !code {
+		indent = hangingIndent(line)
+		if runewidth.StringWidth(indent) > width/2 {
+			// too deep to indent without squeezing the text
+			indent = ""
+		}
+	}
+
+	rows := []wrappedRow{{start: 0}}
+	for start := 0; ; {
+		row := &rows[len(rows)-1]
+		avail := width - runewidth.StringWidth(row.indent)
+
+		// the runes fitting in the row, at least one
+		end, w := start, 0
+		for end < len(runes) {
+			w += runewidth.RuneWidth(runes[end])
+			if w > avail && end > start {
+				break
+			}
+			end++
+		}
+		if end == len(runes) {
+			row.start, row.end = start, end
+			return rows
+		}
+
+		brk := end
+		if !code {
+			for b := end; b > start; b-- {
+				if runes[b-1] == ' ' || runes[b-1] == '\t' {
+					brk = b
+					break
+				}
+			}
+		}
+
+		row.start, row.end = start, brk
+		rows = append(rows, wrappedRow{indent: indent})
+		start = brk
+	}
+}
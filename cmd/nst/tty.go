@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// isPiped reports whether the reader is a pipe or file rather than an
+// interactive terminal, such as when running `kubectl logs ... | nst new`
+func isPiped(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+// isTerminal reports whether the writer is an interactive terminal, where
+// output can be decorated with ANSI escape codes
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
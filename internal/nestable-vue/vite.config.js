@@ -9,10 +9,22 @@ export default defineConfig({
   server: {
       proxy: {
           '/notes': {
-              target: 'http://localhost:3000',
+              target: 'http://127.0.0.1:3000',
               changeOrigin: true,
               secure: false,
               ws: true,
+          },
+          '/api': {
+              target: 'http://127.0.0.1:3000',
+              changeOrigin: true,
+              secure: false,
+              // the API forbids changes from other origins than its own
+              headers: { origin: 'http://127.0.0.1:3000' },
+          },
+          '/wordcloud': {
+              target: 'http://127.0.0.1:3000',
+              changeOrigin: true,
+              secure: false,
           }
       }
   },
@@ -0,0 +1,569 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pokstad/nestable/orm"
+)
+
+// apiVersion is the version of the JSON API served under /api/v1. The
+// latest version is also served under /api.
+const apiVersion = "v1"
+
+// apiNote is a note of the JSON API with its body
+type apiNote struct {
+	note
+	Body string
+	// HTML is the body rendered from markdown
+	HTML string
+}
+
+// apiNoteBody is the request body creating or updating a note
+type apiNoteBody struct {
+	Body string
+	// BaseSHA256 is the revision an update was made from. When the note has
+	// been updated since, the update is rejected.
+	BaseSHA256 string
+}
+
+// apiRendered is the HTML rendered from the markdown of a note
+type apiRendered struct {
+	HTML string
+}
+
+// searchResult is a note matching a full text search
+type searchResult struct {
+	note
+	Score   float32
+	Snippet string
+	// Line is the first line of the note containing a match
+	Line int
+}
+
+// searchResponse is the response of a full text search
+type searchResponse struct {
+	Results []searchResult
+	// Suggestions are corrected queries, best first, when nothing matches
+	Suggestions []string
+}
+
+// api serves the JSON API backed by the repo:
+//
+//	GET    /api/notes                 list the notes, filtered by the query q
+//	POST   /api/notes                 create a note
+//	GET    /api/notes/{id}            get a note with its body
+//	PUT    /api/notes/{id}            update a note
+//	DELETE /api/notes/{id}            move a note to the trash
+//	GET    /api/notes/{id}/revisions  list the revisions of a note, oldest first
+//	       /api/notes/{id}/revisions/{sha}...  a revision, see serveRevision
+//	GET    /api/search?q=             full text search, suggesting corrected
+//	                                  queries when nothing matches
+//	GET    /api/saved                 list the saved searches, like /saved
+//	GET    /api/saved/{name}          run a saved search, like /saved/{name}
+//	GET    /api/wordcloud             the word cloud, like /wordcloud
+//	POST   /api/render                render markdown as HTML, to preview edits
+//
+// Notes have an ETag of their blob SHA256, which PUT takes in If-Match to
+// only update the revision the client has seen. PUT replies 409 Conflict
+// with the current note when the BaseSHA256 of the update isn't the current
+// revision. The revision is checked when the update is saved, so of
+// concurrent updates from the same revision only one succeeds.
+//
+// Request bodies must be JSON, and requests changing notes from other sites
+// are forbidden, so that web pages can't change notes through a browser.
+type api struct {
+	// ctx is the context of the request being served
+	ctx       context.Context
+	repo      orm.Repo
+	wordCloud http.Handler
+}
+
+func newAPI(repo orm.Repo) http.Handler {
+	return api{repo: repo, wordCloud: wordCloudHandler(repo)}
+}
+
+// ServeHTTP serves a request with a copy of the API bound to its context
+func (a api) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	a.ctx = req.Context()
+
+	if crossSite(rw, req) {
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/api")
+	if p := strings.TrimPrefix(path, "/"+apiVersion); strings.HasPrefix(p, "/") {
+		path = p
+	}
+
+	switch {
+	case path == "/notes":
+		a.serveNotes(rw, req)
+	case strings.HasPrefix(path, "/notes/"):
+		idStr, action, _ := strings.Cut(strings.TrimPrefix(path, "/notes/"), "/")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("invalid note ID %q", idStr), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case action == "":
+			a.serveNote(rw, req, id)
+		case action == "revisions":
+			a.serveRevisions(rw, req, id)
+		case strings.HasPrefix(action, "revisions/"):
+			sha, revAction, _ := strings.Cut(strings.TrimPrefix(action, "revisions/"), "/")
+			a.serveRevision(rw, req, id, sha, revAction)
+		default:
+			http.NotFound(rw, req)
+		}
+	case path == "/search":
+		a.serveSearch(rw, req)
+	case path == "/render":
+		a.serveRender(rw, req)
+	case path == "/saved":
+		a.serveSavedSearches(rw, req)
+	case strings.HasPrefix(path, "/saved/"):
+		a.serveSavedSearch(rw, req, strings.TrimPrefix(path, "/saved/"))
+	case path == "/wordcloud":
+		if allowMethods(rw, req, http.MethodGet) {
+			a.wordCloud.ServeHTTP(rw, req)
+		}
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+func (a api) serveNotes(rw http.ResponseWriter, req *http.Request) {
+	if !allowMethods(rw, req, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if req.Method == http.MethodPost {
+		nb, ok := readNoteBody(rw, req)
+		if !ok {
+			return
+		}
+		nr, err := a.repo.NewNote(a.ctx, strings.NewReader(nb.Body))
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		rw.Header().Set("Location", fmt.Sprintf("/api/%s/notes/%d", apiVersion, nr.ID))
+		a.writeNote(rw, http.StatusCreated, nr)
+		return
+	}
+
+	revs, err := queryNotes(a.ctx, a.repo, req.URL.Query().Get("q"))
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	notes := make([]note, len(revs))
+	for i, nr := range revs {
+		if notes[i], err = a.note(nr); err != nil {
+			apiError(rw, err)
+			return
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, notes)
+}
+
+func (a api) serveNote(rw http.ResponseWriter, req *http.Request, id int64) {
+	if !allowMethods(rw, req, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	nr, err := a.currentNoteRev(id)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		if match := req.Header.Get("If-None-Match"); match != "" && match == etag(nr) {
+			rw.Header().Set("ETag", etag(nr))
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		a.writeNote(rw, http.StatusOK, nr)
+
+	case http.MethodPut:
+		if preconditionFailed(rw, req, nr) {
+			return
+		}
+		nb, ok := readNoteBody(rw, req)
+		if !ok {
+			return
+		}
+		if nb.BaseSHA256 != "" && nb.BaseSHA256 != nr.SHA256 {
+			// someone else updated the note since the edit started
+			a.writeNote(rw, http.StatusConflict, nr)
+			return
+		}
+		if updated, ok := a.updateNote(rw, req, nr, nb.Body, nb.BaseSHA256 != ""); ok {
+			a.writeNote(rw, http.StatusOK, updated)
+		}
+
+	case http.MethodDelete:
+		if err := a.repo.TrashNote(a.ctx, id); err != nil {
+			apiError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (a api) serveRevisions(rw http.ResponseWriter, req *http.Request, id int64) {
+	if !allowMethods(rw, req, http.MethodGet) {
+		return
+	}
+
+	if _, err := a.currentNoteRev(id); err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	revs, err := a.repo.GetNoteRevs(a.ctx, id)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	notes := make([]note, len(revs))
+	for i, nr := range revs {
+		if notes[i], err = a.note(nr); err != nil {
+			apiError(rw, err)
+			return
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, notes)
+}
+
+func (a api) serveSearch(rw http.ResponseWriter, req *http.Request) {
+	if !allowMethods(rw, req, http.MethodGet) {
+		return
+	}
+
+	params := req.URL.Query()
+	query := params.Get("q")
+	if query == "" {
+		http.Error(rw, "missing search query q", http.StatusBadRequest)
+		return
+	}
+
+	var opts orm.SearchOptions
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &opts.Limit}, {"offset", &opts.Offset}} {
+		v := params.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(rw, fmt.Sprintf("invalid %s %q", p.name, v), http.StatusBadRequest)
+			return
+		}
+		*p.dst = n
+	}
+
+	results, err := a.repo.FullTextSearchOpts(a.ctx, query, opts)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	found := make([]searchResult, len(results))
+	for i, r := range results {
+		nr, err := r.GetNoteRev(a.ctx, a.repo)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		n, err := a.note(nr)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		found[i] = searchResult{note: n, Score: r.Score, Snippet: r.Snippet, Line: r.Line}
+	}
+
+	suggestions := []string{}
+	if len(found) == 0 && opts.Offset == 0 {
+		corrected, err := a.repo.SuggestQueries(a.ctx, query, 3)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		suggestions = append(suggestions, corrected...)
+	}
+
+	writeJSON(rw, http.StatusOK, searchResponse{Results: found, Suggestions: suggestions})
+}
+
+func (a api) serveSavedSearches(rw http.ResponseWriter, req *http.Request) {
+	if !allowMethods(rw, req, http.MethodGet) {
+		return
+	}
+
+	saved, err := savedSearches(a.ctx, a.repo)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, saved)
+}
+
+func (a api) serveSavedSearch(rw http.ResponseWriter, req *http.Request, name string) {
+	if !allowMethods(rw, req, http.MethodGet) {
+		return
+	}
+
+	notes, err := savedSearchNotes(a.ctx, a.repo, name)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, notes)
+}
+
+func (a api) serveRender(rw http.ResponseWriter, req *http.Request) {
+	if !allowMethods(rw, req, http.MethodPost) {
+		return
+	}
+
+	nb, ok := readNoteBody(rw, req)
+	if !ok {
+		return
+	}
+	html, err := renderMarkdown([]byte(nb.Body))
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, apiRendered{HTML: html})
+}
+
+// updateNote creates a new revision of the note with the body. Conditional
+// updates, with If-Match or from a base revision, are only saved if nr is
+// still the current revision of the note, replying 412 Precondition Failed,
+// or 409 Conflict with the current note for a base revision, otherwise. It
+// returns false when it replied with an error.
+func (a api) updateNote(rw http.ResponseWriter, req *http.Request, nr orm.NoteRev, body string, fromBase bool) (orm.NoteRev, bool) {
+	update := nr.UpdateBlob
+	if match := req.Header.Get("If-Match"); fromBase || (match != "" && match != "*") {
+		update = nr.UpdateBlobIfCurrent
+	}
+
+	updated, err := update(a.ctx, a.repo, strings.NewReader(body))
+	if errors.Is(err, orm.ErrConflict) {
+		// someone else updated the note since it was read
+		current, err := a.currentNoteRev(nr.ID)
+		if err != nil {
+			apiError(rw, err)
+			return orm.NoteRev{}, false
+		}
+		if fromBase {
+			a.writeNote(rw, http.StatusConflict, current)
+		} else {
+			modified(rw, current)
+		}
+		return orm.NoteRev{}, false
+	}
+	if err != nil {
+		apiError(rw, err)
+		return orm.NoteRev{}, false
+	}
+
+	return updated, true
+}
+
+// currentNoteRev returns the current revision of a note, or ErrNotFound
+// when the note is trashed
+func (a api) currentNoteRev(id int64) (orm.NoteRev, error) {
+	nr, err := a.repo.GetCurrentNoteRev(a.ctx, id)
+	if err != nil {
+		return orm.NoteRev{}, err
+	}
+
+	trashed, err := a.repo.IsTrashed(a.ctx, id)
+	if err != nil {
+		return orm.NoteRev{}, err
+	}
+	if trashed {
+		return orm.NoteRev{}, fmt.Errorf("note %d is trashed: %w", id, orm.ErrNotFound)
+	}
+
+	return nr, nil
+}
+
+// note returns the revision with its header
+func (a api) note(nr orm.NoteRev) (note, error) {
+	head, err := nr.GetBlobHead(a.ctx, a.repo, 100)
+	if err != nil {
+		return note{}, err
+	}
+	return note{NoteRev: nr, Header: string(head)}, nil
+}
+
+// writeNote replies with the revision and its body, tagged with its SHA256
+func (a api) writeNote(rw http.ResponseWriter, status int, nr orm.NoteRev) {
+	n, err := a.note(nr)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	body, err := a.readBody(nr)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	html, err := renderMarkdown([]byte(body))
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	rw.Header().Set("ETag", etag(nr))
+	writeJSON(rw, status, apiNote{note: n, Body: body, HTML: html})
+}
+
+// readBody reads the body of a revision
+func (a api) readBody(nr orm.NoteRev) (string, error) {
+	r, err := nr.GetReader(a.ctx, a.repo)
+	if err != nil {
+		return "", fmt.Errorf("getting blob reader: %w", err)
+	}
+	body := new(strings.Builder)
+	if _, err := io.Copy(body, r); err != nil {
+		return "", fmt.Errorf("reading blob: %w", err)
+	}
+	return body.String(), nil
+}
+
+// etag is the entity tag of a note revision
+func etag(nr orm.NoteRev) string {
+	return `"` + nr.SHA256 + `"`
+}
+
+// preconditionFailed replies with 412 Precondition Failed and returns true
+// when the If-Match header of the request isn't the current revision of the
+// note
+func preconditionFailed(rw http.ResponseWriter, req *http.Request, current orm.NoteRev) bool {
+	match := req.Header.Get("If-Match")
+	if match == "" || match == "*" || match == etag(current) {
+		return false
+	}
+	modified(rw, current)
+	return true
+}
+
+// modified replies with 412 Precondition Failed for a note modified since
+// the revision of the request
+func modified(rw http.ResponseWriter, current orm.NoteRev) {
+	http.Error(rw, fmt.Sprintf("note %d was modified, its current revision is %s", current.ID, current.SHA256), http.StatusPreconditionFailed)
+}
+
+// readNoteBody decodes the body of a request creating or updating a note. It
+// replies with an error and returns false when the request is invalid.
+func readNoteBody(rw http.ResponseWriter, req *http.Request) (apiNoteBody, bool) {
+	// browsers send other content types cross-site without a preflight
+	if mt, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		http.Error(rw, "note must be sent as application/json", http.StatusUnsupportedMediaType)
+		return apiNoteBody{}, false
+	}
+
+	var nb apiNoteBody
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&nb); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid note: %v", err), http.StatusBadRequest)
+		return apiNoteBody{}, false
+	}
+	return nb, true
+}
+
+// crossSite replies with 403 Forbidden and returns true when a browser
+// sends a request changing notes from another site, as told by the Origin
+// and Sec-Fetch-Site headers. Requests without them, such as from scripts,
+// are allowed.
+func crossSite(rw http.ResponseWriter, req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	site := req.Header.Get("Sec-Fetch-Site")
+	cross := site != "" && site != "same-origin" && site != "none"
+	if origin := req.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		cross = cross || err != nil || u.Host != req.Host
+	}
+
+	if cross {
+		http.Error(rw, fmt.Sprintf("%s from another site is forbidden", req.Method), http.StatusForbidden)
+	}
+	return cross
+}
+
+// allowMethods replies with 405 Method Not Allowed and returns false unless
+// the request has one of the methods
+func allowMethods(rw http.ResponseWriter, req *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if req.Method == m {
+			return true
+		}
+	}
+	rw.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(rw, fmt.Sprintf("method %s not allowed", req.Method), http.StatusMethodNotAllowed)
+	return false
+}
+
+// apiError replies with the status code matching the error
+func apiError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, orm.ErrNotFound):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+	case errors.Is(err, orm.ErrBadQuery):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON replies with the value encoded as JSON
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if _, err := buf.WriteTo(rw); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Kubernetes\nupgrade the cluster",
+		"# Postgres\nfailover runbook",
+	})
+
+	srv := httptest.NewServer(NewHandler(repo, Options{}))
+	defer srv.Close()
+
+	do := func(method, path, body string, header http.Header) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(b)
+	}
+	decode := func(body string, v interface{}) {
+		t.Helper()
+		require.NoError(t, json.Unmarshal([]byte(body), v))
+	}
+
+	// list and get notes
+	resp, body := do(http.MethodGet, "/api/notes", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var notes []note
+	decode(body, &notes)
+	require.Len(t, notes, 2)
+
+	resp, body = do(http.MethodGet, "/api/v1/notes/1", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `"`+revs[0].SHA256+`"`, resp.Header.Get("ETag"))
+	var n apiNote
+	decode(body, &n)
+	require.Equal(t, revs[0].SHA256, n.SHA256)
+	require.Equal(t, "# Kubernetes", n.Header)
+	require.Equal(t, "# Kubernetes\nupgrade the cluster", n.Body)
+
+	resp, _ = do(http.MethodGet, "/api/notes/1", "", http.Header{"If-None-Match": {resp.Header.Get("ETag")}})
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, _ = do(http.MethodGet, "/api/notes/99", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/api/notes/abc", "", nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(http.MethodPatch, "/api/notes/1", "", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, "GET, PUT, DELETE", resp.Header.Get("Allow"))
+
+	// create a note
+	resp, body = do(http.MethodPost, "/api/notes", `{"Body": "# Redis\ncache eviction"}`, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "/api/v1/notes/3", resp.Header.Get("Location"))
+	decode(body, &n)
+	require.Equal(t, int64(3), n.ID)
+	require.Equal(t, "# Redis\ncache eviction", n.Body)
+
+	resp, _ = do(http.MethodPost, "/api/notes", `{"Text": "unknown field"}`, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// browsers send plain text cross-site without a preflight, so notes
+	// must be JSON
+	resp, _ = do(http.MethodPost, "/api/notes", `{"Body": "from a form"}`,
+		http.Header{"Content-Type": {"text/plain"}})
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp, _ = do(http.MethodPost, "/api/notes", `{"Body": "# Redis\ncache eviction"}`,
+		http.Header{"Content-Type": {"application/json; charset=utf-8"}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// browsers can't change notes from other sites
+	for _, header := range []http.Header{
+		{"Origin": {"https://evil.example"}},
+		{"Origin": {"null"}},
+		{"Sec-Fetch-Site": {"cross-site"}},
+		{"Sec-Fetch-Site": {"same-site"}, "Origin": {srv.URL}},
+	} {
+		resp, _ = do(http.MethodPost, "/api/notes", `{"Body": "forged"}`, header)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, header)
+		resp, _ = do(http.MethodPut, "/api/notes/1", `{"Body": "forged"}`, header)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, header)
+		resp, _ = do(http.MethodDelete, "/api/notes/1", "", header)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, header)
+	}
+	resp, _ = do(http.MethodGet, "/api/notes/1", "", http.Header{"Origin": {"https://evil.example"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(http.MethodPost, "/api/render", `{"Body": "same origin"}`,
+		http.Header{"Origin": {srv.URL}, "Sec-Fetch-Site": {"same-origin"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// update a note only if it wasn't modified since it was read
+	resp, body = do(http.MethodPut, "/api/notes/2", `{"Body": "# Postgres\nfailover and backups"}`,
+		http.Header{"If-Match": {`"` + revs[1].SHA256 + `"`}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	decode(body, &n)
+	require.Equal(t, "# Postgres\nfailover and backups", n.Body)
+	require.Equal(t, `"`+n.SHA256+`"`, resp.Header.Get("ETag"))
+
+	resp, _ = do(http.MethodPut, "/api/notes/2", `{"Body": "stale"}`,
+		http.Header{"If-Match": {`"` + revs[1].SHA256 + `"`}})
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, body = do(http.MethodGet, "/api/notes/2/revisions", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	decode(body, &notes)
+	require.Len(t, notes, 2)
+	require.Equal(t, revs[1].SHA256, notes[0].SHA256)
+	require.Equal(t, n.SHA256, notes[1].SHA256)
+
+	// search
+	resp, body = do(http.MethodGet, "/api/search?q=failover", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var results searchResponse
+	decode(body, &results)
+	require.Len(t, results.Results, 1)
+	require.Equal(t, int64(2), results.Results[0].ID)
+	require.Equal(t, 2, results.Results[0].Line)
+	require.Empty(t, results.Suggestions)
+
+	resp, body = do(http.MethodGet, "/api/search?q=kubernetis", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"Results":[],"Suggestions":["kubernetes"]}`+"\n", body)
+
+	resp, _ = do(http.MethodGet, "/api/search?q=%22failover", "", nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/api/search", "", nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/api/notes?q=text:%22failover", "", nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// saved searches
+	require.NoError(t, repo.SaveSearch(ctx, orm.SavedSearch{Name: "db", Query: "failover"}))
+	resp, body = do(http.MethodGet, "/api/saved", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var saved []savedSearch
+	decode(body, &saved)
+	require.Len(t, saved, 1)
+	require.Equal(t, "db", saved[0].Name)
+	require.Equal(t, 1, saved[0].Changed)
+
+	resp, body = do(http.MethodGet, "/api/v1/saved/db", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	decode(body, &notes)
+	require.Len(t, notes, 1)
+	require.Equal(t, int64(2), notes[0].ID)
+
+	resp, _ = do(http.MethodGet, "/api/saved/unknown", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// trash a note
+	resp, _ = do(http.MethodDelete, "/api/notes/2", "", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = do(http.MethodGet, "/api/notes/2", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = do(http.MethodDelete, "/api/notes/99", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, body = do(http.MethodGet, "/api/search?q=failover", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"Results":[],"Suggestions":[]}`+"\n", body)
+
+	// word cloud
+	resp, body = do(http.MethodGet, "/api/wordcloud", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, body, `"Term":"kubernetes"`)
+
+	resp, _ = do(http.MethodGet, "/api/unknown", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAPIEdit(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"# Runbook\n\n- [x] drain the node"})
+
+	srv := httptest.NewServer(NewHandler(repo, Options{}))
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, apiNote) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var n apiNote
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&n))
+		return resp.StatusCode, n
+	}
+
+	// notes are rendered as HTML
+	status, n := do(http.MethodGet, "/api/notes/1", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "<h1>Runbook</h1>\n<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> drain the node</li>\n</ul>\n", n.HTML)
+
+	// an edit from the current revision is saved
+	update, err := json.Marshal(apiNoteBody{Body: "# Runbook\n\n- [x] drain the node\n- [ ] reboot", BaseSHA256: revs[0].SHA256})
+	require.NoError(t, err)
+	status, updated := do(http.MethodPut, "/api/notes/1", string(update))
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, updated.HTML, "reboot")
+
+	// an edit from an older revision conflicts with the current revision
+	stale, err := json.Marshal(apiNoteBody{Body: "# Runbook\n\noverwritten", BaseSHA256: revs[0].SHA256})
+	require.NoError(t, err)
+	status, current := do(http.MethodPut, "/api/notes/1", string(stale))
+	require.Equal(t, http.StatusConflict, status)
+	require.Equal(t, updated.SHA256, current.SHA256)
+	require.Equal(t, updated.Body, current.Body)
+
+	// previews are rendered without saving, leaving out raw HTML and
+	// dangerous links
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/render",
+		strings.NewReader(`{"Body": "**bold** <script>alert(1)</script> [x](javascript:alert(1))"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rendered apiRendered
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rendered))
+	require.Equal(t, "<p><strong>bold</strong> <!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted --> <a href=\"\">x</a></p>\n", rendered.HTML)
+
+	revisions, err := repo.GetNoteRevs(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	// of concurrent edits from the same revision only one is saved, the
+	// other conflicts
+	put := func(body, base string) (int, error) {
+		edit, err := json.Marshal(apiNoteBody{Body: body, BaseSHA256: base})
+		if err != nil {
+			return 0, err
+		}
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/notes/1", bytes.NewReader(edit))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	for i := 0; i < 5; i++ {
+		current, err := repo.GetCurrentNoteRev(ctx, 1)
+		require.NoError(t, err)
+
+		var (
+			wg       sync.WaitGroup
+			statuses [2]int
+			errs     [2]error
+		)
+		for j := range statuses {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				statuses[j], errs[j] = put(fmt.Sprintf("# Runbook\n\nedit %d of round %d", j, i), current.SHA256)
+			}(j)
+		}
+		wg.Wait()
+		require.NoError(t, errs[0])
+		require.NoError(t, errs[1])
+		require.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, statuses[:])
+	}
+
+	revisions, err = repo.GetNoteRevs(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, revisions, 7)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// fuzzyNote is a note whose header matches a fuzzy search
+type fuzzyNote struct {
+	note
+	// Matches are the indexes of the characters of the header matching the
+	// search, counted in Unicode code points
+	Matches []int
+}
+
+// fuzzyNotes returns the notes whose header contains the characters of the
+// search in order, ignoring case and diacritics, the closest match first
+func fuzzyNotes(search string, notes []note) []fuzzyNote {
+	headers := make([]string, len(notes))
+	for i, n := range notes {
+		headers[i] = n.Header
+	}
+
+	ranks := fuzzy.RankFindNormalizedFold(search, headers)
+	// notes matching as closely keep their order
+	sort.Stable(ranks)
+
+	found := make([]fuzzyNote, len(ranks))
+	for i, r := range ranks {
+		n := notes[r.OriginalIndex]
+		found[i] = fuzzyNote{note: n, Matches: fuzzyMatches(search, n.Header)}
+	}
+	return found
+}
+
+// normalizeFold removes the diacritics of s and lowers its case, like the
+// fuzzy package does before matching
+func normalizeFold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, err := transform.String(t, s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return strings.ToLower(normalized)
+}
+
+// fuzzyMatches returns the indexes of the code points of the target matching
+// the characters of the source, taking the first match of each character
+func fuzzyMatches(source, target string) []int {
+	rest := normalizeFold(source)
+
+	var matches []int
+	for i, r := range []rune(target) {
+		if rest == "" {
+			break
+		}
+		c := normalizeFold(string(r))
+		if c != "" && strings.HasPrefix(rest, c) {
+			matches = append(matches, i)
+			rest = rest[len(c):]
+		}
+	}
+	return matches
+}
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyNotes(t *testing.T) {
+	notes := []note{
+		{NoteRev: orm.NoteRev{Note: orm.Note{ID: 1}}, Header: "# Kubernetes upgrade"},
+		{NoteRev: orm.NoteRev{Note: orm.Note{ID: 2}}, Header: "# Postgres failover"},
+		{NoteRev: orm.NoteRev{Note: orm.Note{ID: 3}}, Header: "# Küche"},
+		{NoteRev: orm.NoteRev{Note: orm.Note{ID: 4}}, Header: "kube"},
+	}
+
+	ids := func(found []fuzzyNote) []int64 {
+		ids := []int64{}
+		for _, n := range found {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+
+	// the closest match comes first
+	found := fuzzyNotes("kube", notes)
+	require.Equal(t, []int64{4, 1}, ids(found))
+	require.Equal(t, []int{0, 1, 2, 3}, found[0].Matches)
+	require.Equal(t, []int{2, 3, 4, 5}, found[1].Matches)
+
+	// case and diacritics are ignored
+	found = fuzzyNotes("KUCH", notes)
+	require.Equal(t, []int64{3}, ids(found))
+	require.Equal(t, []int{2, 3, 4, 5}, found[0].Matches)
+
+	found = fuzzyNotes("pgfail", notes)
+	require.Equal(t, []int64{2}, ids(found))
+	require.Equal(t, []int{2, 6, 11, 12, 13, 14}, found[0].Matches)
+
+	require.Empty(t, fuzzyNotes("redis", notes))
+}
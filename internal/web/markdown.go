@@ -0,0 +1,23 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders notes as HTML with GitHub flavored markdown. Raw HTML and
+// dangerous links such as javascript: URLs are left out, so that the HTML is
+// safe to show.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// renderMarkdown renders the markdown of a note as HTML
+func renderMarkdown(src []byte) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := markdown.Convert(src, buf); err != nil {
+		return "", fmt.Errorf("rendering markdown: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/pokstad/nestable/orm"
+)
+
+// Ops of diff lines
+const (
+	diffEqual   = "="
+	diffRemoved = "-"
+	diffAdded   = "+"
+)
+
+// diffLine is a line of the diff between two revisions of a note
+type diffLine struct {
+	// Op is "=" for a line of both revisions, "-" for a line only in the old
+	// revision and "+" for a line only in the new revision
+	Op   string
+	Text string
+	// OldLine and NewLine are the numbers of the line in the old and new
+	// revisions, starting at 1, or zero when the line isn't in the revision
+	OldLine, NewLine int
+}
+
+// revisionDiff is the diff between a revision of a note and its current
+// revision
+type revisionDiff struct {
+	From, To note
+	// Lines are all the lines of both revisions, for inline and side by
+	// side views
+	Lines []diffLine
+	// Unified is the diff in the unified format with 3 lines of context
+	Unified string
+}
+
+// serveRevision serves a revision of a note:
+//
+//	GET  /api/notes/{id}/revisions/{sha}          the revision with its body
+//	GET  /api/notes/{id}/revisions/{sha}/diff     its diff against the current revision
+//	POST /api/notes/{id}/revisions/{sha}/restore  a new current revision restoring it
+//
+// The SHA256 of the revision can be shortened to a unique prefix. Restoring
+// requires the ETag of the current revision in If-Match, so that the
+// revision restored over is the one the client has seen, replying 428
+// Precondition Required without it.
+func (a api) serveRevision(rw http.ResponseWriter, req *http.Request, id int64, sha, action string) {
+	current, err := a.currentNoteRev(id)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+	rev, err := a.repo.FindNoteRev(a.ctx, id, sha)
+	if err != nil {
+		apiError(rw, err)
+		return
+	}
+
+	switch action {
+	case "":
+		if allowMethods(rw, req, http.MethodGet) {
+			a.writeNote(rw, http.StatusOK, rev)
+		}
+
+	case "diff":
+		if !allowMethods(rw, req, http.MethodGet) {
+			return
+		}
+		diff, err := a.diff(rev, current)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		writeJSON(rw, http.StatusOK, diff)
+
+	case "restore":
+		if !allowMethods(rw, req, http.MethodPost) {
+			return
+		}
+		if match := req.Header.Get("If-Match"); match == "" || match == "*" {
+			http.Error(rw, "restoring a revision requires the ETag of the current revision in If-Match", http.StatusPreconditionRequired)
+			return
+		}
+		if preconditionFailed(rw, req, current) {
+			return
+		}
+		if rev.SHA256 == current.SHA256 {
+			a.writeNote(rw, http.StatusOK, current)
+			return
+		}
+		body, err := a.readBody(rev)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+		if restored, ok := a.updateNote(rw, req, current, body, false); ok {
+			a.writeNote(rw, http.StatusOK, restored)
+		}
+
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+// diff returns the diff from one revision to another
+func (a api) diff(from, to orm.NoteRev) (revisionDiff, error) {
+	fromNote, err := a.note(from)
+	if err != nil {
+		return revisionDiff{}, err
+	}
+	toNote, err := a.note(to)
+	if err != nil {
+		return revisionDiff{}, err
+	}
+
+	fromBody, err := a.readBody(from)
+	if err != nil {
+		return revisionDiff{}, err
+	}
+	toBody, err := a.readBody(to)
+	if err != nil {
+		return revisionDiff{}, err
+	}
+
+	oldLines, newLines := difflib.SplitLines(fromBody), difflib.SplitLines(toBody)
+	unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        oldLines,
+		B:        newLines,
+		FromFile: fmt.Sprintf("note %d rev %.8s", from.ID, from.SHA256),
+		ToFile:   fmt.Sprintf("note %d rev %.8s", to.ID, to.SHA256),
+		Context:  3,
+	})
+	if err != nil {
+		return revisionDiff{}, fmt.Errorf("diffing note %d: %w", from.ID, err)
+	}
+
+	return revisionDiff{
+		From:    fromNote,
+		To:      toNote,
+		Lines:   diffLines(oldLines, newLines),
+		Unified: unified,
+	}, nil
+}
+
+// diffLines returns the lines of both revisions, marking the lines removed
+// from the old revision and added to the new revision. Changed lines are
+// removed before they are added.
+func diffLines(oldLines, newLines []string) []diffLine {
+	var lines []diffLine
+	line := func(op, text string, oldLine, newLine int) {
+		lines = append(lines, diffLine{
+			Op:      op,
+			Text:    strings.TrimSuffix(text, "\n"),
+			OldLine: oldLine,
+			NewLine: newLine,
+		})
+	}
+
+	// without autojunk, frequent lines such as blank lines and fences of
+	// long notes still match
+	m := difflib.NewMatcherWithJunk(oldLines, newLines, false, nil)
+	for _, op := range m.GetOpCodes() {
+		if op.Tag == 'e' {
+			for i := op.I1; i < op.I2; i++ {
+				line(diffEqual, oldLines[i], i+1, op.J1+i-op.I1+1)
+			}
+			continue
+		}
+		// replaced, deleted or inserted lines
+		for i := op.I1; i < op.I2; i++ {
+			line(diffRemoved, oldLines[i], i+1, 0)
+		}
+		for j := op.J1; j < op.J2; j++ {
+			line(diffAdded, newLines[j], 0, j+1)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisions(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"# Runbook\ndrain\nreboot\nuncordon"})
+	current, err := revs[0].UpdateBlob(ctx, repo, bytes.NewBufferString("# Runbook\ndrain\nupgrade\nreboot"))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(NewHandler(repo, Options{}))
+	defer srv.Close()
+
+	do := func(method, path string, header http.Header, status int, v interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		for k, vals := range header {
+			req.Header[k] = vals
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode)
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+	}
+
+	// a revision by a prefix of its SHA256
+	var n apiNote
+	do(http.MethodGet, "/api/notes/1/revisions/"+revs[0].SHA256[:8], nil, http.StatusOK, &n)
+	require.Equal(t, revs[0].SHA256, n.SHA256)
+	require.Equal(t, "# Runbook\ndrain\nreboot\nuncordon", n.Body)
+	require.Equal(t, "<h1>Runbook</h1>\n<p>drain\nreboot\nuncordon</p>\n", n.HTML)
+
+	do(http.MethodGet, "/api/notes/1/revisions/ffff", nil, http.StatusNotFound, nil)
+	do(http.MethodGet, "/api/notes/2/revisions/"+revs[0].SHA256, nil, http.StatusNotFound, nil)
+	do(http.MethodGet, "/api/notes/1/revisions/"+revs[0].SHA256+"/unknown", nil, http.StatusNotFound, nil)
+
+	var diff revisionDiff
+	do(http.MethodGet, "/api/notes/1/revisions/"+revs[0].SHA256+"/diff", nil, http.StatusOK, &diff)
+	require.Equal(t, revs[0].SHA256, diff.From.SHA256)
+	require.Equal(t, current.SHA256, diff.To.SHA256)
+	require.Equal(t, []diffLine{
+		{Op: "=", Text: "# Runbook", OldLine: 1, NewLine: 1},
+		{Op: "=", Text: "drain", OldLine: 2, NewLine: 2},
+		{Op: "+", Text: "upgrade", NewLine: 3},
+		{Op: "=", Text: "reboot", OldLine: 3, NewLine: 4},
+		{Op: "-", Text: "uncordon", OldLine: 4},
+	}, diff.Lines)
+	require.Contains(t, diff.Unified, "-uncordon\n")
+	require.Contains(t, diff.Unified, "+upgrade\n")
+
+	// restoring a revision creates a new current revision with its body
+	do(http.MethodGet, "/api/notes/1/revisions/"+revs[0].SHA256+"/restore", nil, http.StatusMethodNotAllowed, nil)
+	restorePath := "/api/notes/1/revisions/" + revs[0].SHA256[:8] + "/restore"
+	do(http.MethodPost, restorePath, nil, http.StatusPreconditionRequired, nil)
+	do(http.MethodPost, restorePath, http.Header{"If-Match": {"*"}}, http.StatusPreconditionRequired, nil)
+	do(http.MethodPost, restorePath, http.Header{"If-Match": {`"` + revs[0].SHA256 + `"`}}, http.StatusPreconditionFailed, nil)
+	do(http.MethodPost, restorePath, http.Header{
+		"If-Match": {`"` + current.SHA256 + `"`},
+		"Origin":   {"https://evil.example"},
+	}, http.StatusForbidden, nil)
+	do(http.MethodPost, restorePath, http.Header{"If-Match": {`"` + current.SHA256 + `"`}}, http.StatusOK, &n)
+	require.Equal(t, revs[0].SHA256, n.SHA256)
+	require.Equal(t, "# Runbook\ndrain\nreboot\nuncordon", n.Body)
+
+	var revisions []note
+	do(http.MethodGet, "/api/notes/1/revisions", nil, http.StatusOK, &revisions)
+	require.Len(t, revisions, 3)
+	require.Equal(t, revs[0].SHA256, revisions[2].SHA256)
+
+	// of concurrent restores from the same revision only one is saved, the
+	// other fails its precondition
+	restore := func(sha, match string) (int, error) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/notes/1/revisions/"+sha+"/restore", nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("If-Match", `"`+match+`"`)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	for i, sha := range []string{current.SHA256, revs[0].SHA256, current.SHA256} {
+		match := revs[0].SHA256
+		if i%2 == 1 {
+			match = current.SHA256
+		}
+
+		var (
+			wg       sync.WaitGroup
+			statuses [2]int
+			errs     [2]error
+		)
+		for j := range statuses {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				statuses[j], errs[j] = restore(sha, match)
+			}(j)
+		}
+		wg.Wait()
+		require.NoError(t, errs[0])
+		require.NoError(t, errs[1])
+		require.ElementsMatch(t, []int{http.StatusOK, http.StatusPreconditionFailed}, statuses[:])
+	}
+
+	do(http.MethodGet, "/api/notes/1/revisions", nil, http.StatusOK, &revisions)
+	require.Len(t, revisions, 6)
+}
+
+func TestDiffLinesLongNote(t *testing.T) {
+	// blank lines and fences are frequent in long notes, yet still match
+	step := []string{"- [ ] check the logs\n", "\n", "```\n", "kubectl get pods\n", "```\n", "\n"}
+	var oldLines []string
+	for i := 0; i < 210; i++ {
+		if i%30 == 0 {
+			oldLines = append(oldLines, fmt.Sprintf("## Step %d\n", i/30))
+			continue
+		}
+		oldLines = append(oldLines, step[i%len(step)])
+	}
+	newLines := append(append(append([]string{}, oldLines[:181]...), "\n"), oldLines[181:]...)
+
+	var changed []diffLine
+	for _, l := range diffLines(oldLines, newLines) {
+		if l.Op != diffEqual {
+			changed = append(changed, l)
+		}
+	}
+	require.Equal(t, []diffLine{{Op: "+", Text: "", NewLine: 183}}, changed)
+}
@@ -4,13 +4,22 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/pokstad/nestable/internal/wordcloud"
 	"github.com/pokstad/nestable/orm"
 )
 
@@ -19,25 +28,199 @@ var (
 	staticFS embed.FS
 )
 
+// DefaultAddr is the address the web server listens on by default, only
+// reachable from this machine
+const DefaultAddr = "127.0.0.1:3000"
+
+// shutdownTimeout is how long the web server waits for the requests in
+// flight when shutting down
+const shutdownTimeout = 5 * time.Second
+
+// Options configure the web server
+type Options struct {
+	// Addr is the TCP address to listen on, DefaultAddr when empty
+	Addr string
+	// OpenBrowser opens the web UI in the default browser once the server
+	// listens
+	OpenBrowser bool
+	// Log receives a line for each request, requests aren't logged when nil
+	Log io.Writer
+}
+
 type note struct {
 	orm.NoteRev
 	Header string
 }
 
-func Serve(ctx context.Context, repo orm.Repo, r io.Reader, w io.Writer) error {
-	srv := &http.Server{Addr: ":3000"}
-	defer srv.Close()
+// relatedNote is a note with its similarity to another note
+type relatedNote struct {
+	note
+	Similarity float64
+}
+
+// savedSearch is a saved search with the number of matching notes changed
+// since it last ran
+type savedSearch struct {
+	orm.SavedSearch
+	Changed int
+}
 
-	subStaticFS, err := fs.Sub(staticFS, "static")
+// queryNotes returns the notes matching a note query, or all notes when the
+// query is empty
+func queryNotes(ctx context.Context, repo orm.Repo, query string) ([]orm.NoteRev, error) {
+	if query == "" {
+		return repo.GetNotes(ctx)
+	}
+
+	q, err := orm.ParseQuery(query)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	results, err := repo.QueryNotes(ctx, q, orm.SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]orm.NoteRev, len(results))
+	for i, r := range results {
+		notes[i] = r.NoteRev
+	}
+	return notes, nil
+}
+
+// wordCloudTerms returns the terms of the word cloud of the notes modified
+// within the window, with the frequent phrases when phrases is true
+func wordCloudTerms(ctx context.Context, repo orm.Repo, window orm.TimeWindow, phrases bool) ([]orm.WCTerm, error) {
+	terms, err := repo.WordCloudTermsIn(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+	if phrases {
+		wcPhrases, err := repo.WordCloudPhrases(ctx, window)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, wcPhrases...)
+		sort.SliceStable(terms, func(i, j int) bool { return terms[i].InstanceCount > terms[j].InstanceCount })
+	}
+	return terms, nil
+}
+
+// savedSearches returns the saved searches with the number of matching
+// notes changed since each last ran
+func savedSearches(ctx context.Context, repo orm.Repo) ([]savedSearch, error) {
+	searches, err := repo.GetSavedSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := make([]savedSearch, len(searches))
+	for i, ss := range searches {
+		saved[i] = savedSearch{SavedSearch: ss}
+		if saved[i].Changed, err = ss.Changed(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	return saved, nil
+}
+
+// savedSearchNotes runs the saved search with the name and returns the
+// notes it found, best match first
+func savedSearchNotes(ctx context.Context, repo orm.Repo, name string) ([]note, error) {
+	query, err := repo.ResolveSearch(ctx, "@"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := repo.FullTextSearch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	wNotes := make([]note, len(results))
+	for i, r := range results {
+		nr, err := r.GetNoteRev(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+		head, err := nr.GetBlobHead(ctx, repo, 100)
+		if err != nil {
+			return nil, err
+		}
+		wNotes[i] = note{NoteRev: nr, Header: string(head)}
+	}
+	return wNotes, nil
+}
+
+// wordCloudHandler serves the word cloud as JSON, with the terms of the
+// notes modified within the since or between parameters, the trending terms
+// when trending is true and the frequent phrases when phrases is true
+func wordCloudHandler(repo orm.Repo) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		ctx := req.Context()
+
+		params := req.URL.Query()
+		window, err := orm.ParseTimeWindow(params.Get("since"), params.Get("between"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var terms interface{}
+		if trending, _ := strconv.ParseBool(params.Get("trending")); trending {
+			if window.IsZero() {
+				http.Error(rw, "trending terms require a time window, set since or between", http.StatusBadRequest)
+				return
+			}
+			trendingTerms, err := repo.TrendingTerms(ctx, window)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if trendingTerms == nil {
+				trendingTerms = []orm.TrendingTerm{}
+			}
+			terms = trendingTerms
+		} else {
+			phrases, _ := strconv.ParseBool(params.Get("phrases"))
+			wcTerms, err := wordCloudTerms(ctx, repo, window, phrases)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if wcTerms == nil {
+				wcTerms = []orm.WCTerm{}
+			}
+			terms = wcTerms
+		}
+
+		if err := json.NewEncoder(rw).Encode(terms); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
+}
+
+// NewHandler returns the handler of the web UI and JSON API backed by the
+// repo
+func NewHandler(repo orm.Repo, opts Options) http.Handler {
+	// the static directory is embedded, so it always exists
+	subStaticFS, _ := fs.Sub(staticFS, "static")
 
-	http.Handle("/", http.FileServer(http.FS(subStaticFS)))
-	http.HandleFunc("/notes", func(rw http.ResponseWriter, req *http.Request) {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(subStaticFS)))
+	mux.Handle("/api/", newAPI(repo))
+	mux.HandleFunc("/notes", func(rw http.ResponseWriter, req *http.Request) {
 		defer req.Body.Close()
+		ctx := req.Context()
 
-		allNotes, err := repo.GetNotes(ctx)
+		allNotes, err := queryNotes(ctx, repo, req.URL.Query().Get("q"))
+		if errors.Is(err, orm.ErrBadQuery) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
@@ -59,31 +242,280 @@ func Serve(ctx context.Context, repo orm.Repo, r io.Reader, w io.Writer) error {
 			wNotes[i].Header = allHeaders[i]
 		}
 
-		fuzzySearch := req.URL.Query().Get("fuzzy")
-		if fuzzySearch != "" {
-			ranks := fuzzy.RankFindNormalizedFold(fuzzySearch, allHeaders)
-			sort.Sort(ranks)
-			log.Print(ranks)
+		var found interface{} = wNotes
+		if fuzzySearch := req.URL.Query().Get("fuzzy"); fuzzySearch != "" {
+			found = fuzzyNotes(fuzzySearch, wNotes)
 		}
 
 		enc := json.NewEncoder(rw)
-		if err := enc.Encode(wNotes); err != nil {
+		if err := enc.Encode(found); err != nil {
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	})
 
-	log.Print("Listening on :3000...")
-	errQ := make(chan error)
-	go func() { errQ <- srv.ListenAndServe() }()
+	mux.HandleFunc("/notes/", func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		ctx := req.Context()
+
+		// only /notes/{id}/related is served below /notes/
+		idStr, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/notes/"), "/")
+		if action != "related" {
+			http.NotFound(rw, req)
+			return
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("invalid note ID %q", idStr), http.StatusBadRequest)
+			return
+		}
+
+		n := 5
+		if nStr := req.URL.Query().Get("n"); nStr != "" {
+			if n, err = strconv.Atoi(nStr); err != nil {
+				http.Error(rw, fmt.Sprintf("invalid number of related notes %q", nStr), http.StatusBadRequest)
+				return
+			}
+		}
+
+		related, err := repo.RelatedNotes(ctx, id, n)
+		if errors.Is(err, orm.ErrNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rNotes := make([]relatedNote, len(related))
+		for i, rn := range related {
+			head, err := rn.GetBlobHead(ctx, repo, 100)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rNotes[i] = relatedNote{
+				note:       note{NoteRev: rn.NoteRev, Header: string(head)},
+				Similarity: rn.Similarity,
+			}
+		}
+
+		if err := json.NewEncoder(rw).Encode(rNotes); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+	mux.HandleFunc("/suggest", func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		ctx := req.Context()
+
+		query := req.URL.Query().Get("q")
+		if query == "" {
+			http.Error(rw, "missing full text search query q", http.StatusBadRequest)
+			return
+		}
+
+		n := 3
+		if nStr := req.URL.Query().Get("n"); nStr != "" {
+			var err error
+			if n, err = strconv.Atoi(nStr); err != nil {
+				http.Error(rw, fmt.Sprintf("invalid number of suggestions %q", nStr), http.StatusBadRequest)
+				return
+			}
+		}
+
+		suggestions, err := repo.SuggestQueries(ctx, query, n)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if suggestions == nil {
+			suggestions = []string{}
+		}
+
+		if err := json.NewEncoder(rw).Encode(suggestions); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+	mux.HandleFunc("/wordcloud", wordCloudHandler(repo))
+	mux.HandleFunc("/wordcloud.svg", func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		ctx := req.Context()
+
+		params := req.URL.Query()
+		window, err := orm.ParseTimeWindow(params.Get("since"), params.Get("between"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var terms []orm.WCTerm
+		if trending, _ := strconv.ParseBool(params.Get("trending")); trending {
+			if window.IsZero() {
+				http.Error(rw, "trending terms require a time window, set since or between", http.StatusBadRequest)
+				return
+			}
+			trendingTerms, err := repo.TrendingTerms(ctx, window)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, t := range trendingTerms {
+				terms = append(terms, t.WCTerm)
+			}
+		} else {
+			phrases, _ := strconv.ParseBool(params.Get("phrases"))
+			if terms, err = wordCloudTerms(ctx, repo, window, phrases); err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		words := make([]wordcloud.Word, len(terms))
+		for i, t := range terms {
+			words[i] = wordcloud.Word{Text: t.Term, Weight: t.InstanceCount}
+		}
+
+		rw.Header().Set("Content-Type", "image/svg+xml")
+		err = wordcloud.WriteSVG(rw, words, wordcloud.Options{
+			// each word links to the page of the web UI searching the
+			// notes containing it
+			Link: func(w wordcloud.Word) string {
+				query := w.Text
+				if strings.Contains(query, " ") {
+					query = `"` + query + `"`
+				}
+				return "/#/search/" + url.PathEscape(query)
+			},
+		})
+		if err != nil {
+			log.Printf("writing word cloud: %v", err)
+		}
+	})
+	mux.HandleFunc("/saved", func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+
+		saved, err := savedSearches(req.Context(), repo)
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+
+		if err := json.NewEncoder(rw).Encode(saved); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+	mux.HandleFunc("/saved/", func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+
+		wNotes, err := savedSearchNotes(req.Context(), repo, strings.TrimPrefix(req.URL.Path, "/saved/"))
+		if err != nil {
+			apiError(rw, err)
+			return
+		}
+
+		if err := json.NewEncoder(rw).Encode(wNotes); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	if opts.Log == nil {
+		return mux
+	}
+	return logRequests(mux, opts.Log)
+}
+
+// logRequests logs a line for each request served by the handler
+func logRequests(h http.Handler, w io.Writer) http.Handler {
+	logger := log.New(w, "", log.LstdFlags)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		h.ServeHTTP(sr, req)
+		logger.Printf("%s %s %d %s", req.Method, req.URL.RequestURI(), sr.status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+// statusRecorder records the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Serve serves the web UI and JSON API until the context is canceled, then
+// waits for the requests in flight before returning
+func Serve(ctx context.Context, repo orm.Repo, opts Options) error {
+	addr := opts.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: NewHandler(repo, opts)}
+
+	url := serverURL(ln.Addr())
+	log.Printf("Listening on %s...", url)
+	if opts.OpenBrowser {
+		if err := openBrowser(url); err != nil {
+			log.Printf("opening browser: %v", err)
+		}
+	}
+
+	errQ := make(chan error, 1)
+	go func() { errQ <- srv.Serve(ln) }()
 
 	select {
+	case err := <-errQ:
+		return err
 	case <-ctx.Done():
-		err = srv.Shutdown(ctx)
-		break
-	case err = <-errQ:
-		break
 	}
 
-	return err
+	log.Print("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down web server: %w", err)
+	}
+
+	return nil
+}
+
+// serverURL returns the URL of the server listening on the address, on
+// localhost when it listens on all interfaces
+func serverURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// openBrowser opens the URL in the default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
 }
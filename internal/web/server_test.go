@@ -1,7 +1,105 @@
 package web
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/stretchr/testify/require"
+)
 
 func TestServer(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Kubernetes upgrade\nupgrade the kubernetes cluster",
+		"# Postgres failover\nfailover the postgres cluster",
+		"# Kubernetes ingress\nkubernetes ingress for the cluster",
+	})
+
+	logs := bytes.NewBuffer(nil)
+	srv := httptest.NewServer(NewHandler(repo, Options{Log: logs}))
+	defer srv.Close()
+
+	get := func(path string, status int) string {
+		t.Helper()
+		resp, err := srv.Client().Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, status, resp.StatusCode, string(body))
+		return string(body)
+	}
+	decode := func(body string, v interface{}) {
+		t.Helper()
+		require.NoError(t, json.Unmarshal([]byte(body), v))
+	}
+
+	require.Contains(t, get("/", http.StatusOK), `<div id="app"></div>`)
+
+	var notes []note
+	decode(get("/notes", http.StatusOK), &notes)
+	require.Len(t, notes, 3)
+	decode(get("/notes?q=postgres", http.StatusOK), &notes)
+	require.Len(t, notes, 1)
+	get("/notes?q=text:%22postgres", http.StatusBadRequest)
+
+	var found []fuzzyNote
+	decode(get("/notes?fuzzy=kubup", http.StatusOK), &found)
+	require.Len(t, found, 1)
+	require.Equal(t, int64(1), found[0].ID)
+	require.Equal(t, []int{2, 3, 4, 13, 14}, found[0].Matches)
+
+	var related []relatedNote
+	decode(get("/notes/1/related?n=1", http.StatusOK), &related)
+	require.Len(t, related, 1)
+	require.Equal(t, int64(3), related[0].ID)
+	get("/notes/99/related", http.StatusNotFound)
+	get("/notes/x/related", http.StatusBadRequest)
+
+	var suggestions []string
+	decode(get("/suggest?q=kubernetis", http.StatusOK), &suggestions)
+	require.Equal(t, []string{"kubernetes"}, suggestions)
+	get("/suggest", http.StatusBadRequest)
+
+	require.Contains(t, get("/wordcloud", http.StatusOK), `"Term":"kubernetes"`)
+	get("/wordcloud?since=soon", http.StatusBadRequest)
+	require.Contains(t, get("/wordcloud.svg", http.StatusOK), `<a href="/#/search/kubernetes">`)
+
+	require.Equal(t, "[]\n", get("/saved", http.StatusOK))
+	get("/saved/unknown", http.StatusNotFound)
+
+	require.Contains(t, logs.String(), "GET /saved/unknown 404")
+}
+
+func TestServe(t *testing.T) {
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errQ := make(chan error)
+	go func() { errQ <- Serve(ctx, repo, Options{Addr: "127.0.0.1:0"}) }()
+
+	// the server shuts down gracefully once the context is canceled
+	cancel()
+	select {
+	case err := <-errQ:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server didn't shut down")
+	}
 
+	require.Error(t, Serve(context.Background(), repo, Options{Addr: "127.0.0.1:-1"}))
 }
@@ -0,0 +1,208 @@
+// Package wordcloud lays out weighted words as a word cloud and draws it as
+// an SVG image.
+package wordcloud
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// Word is a word of the cloud. Heavier words are drawn larger.
+type Word struct {
+	Text   string
+	Weight int64
+}
+
+// Options configure the layout of a word cloud. Zero values use the
+// defaults.
+type Options struct {
+	Width, Height int
+	// MinFontSize and MaxFontSize are the font sizes of the lightest and
+	// heaviest words, in pixels
+	MinFontSize, MaxFontSize float64
+	// MaxWords is the number of heaviest words laid out
+	MaxWords int
+	// Link returns the URL a word links to, no word links when nil
+	Link func(Word) string
+}
+
+// DefaultOptions are the options used for zero values
+var DefaultOptions = Options{
+	Width:       800,
+	Height:      600,
+	MinFontSize: 12,
+	MaxFontSize: 72,
+	MaxWords:    100,
+}
+
+func (o Options) withDefaults() Options {
+	if o.Width <= 0 {
+		o.Width = DefaultOptions.Width
+	}
+	if o.Height <= 0 {
+		o.Height = DefaultOptions.Height
+	}
+	if o.MinFontSize <= 0 {
+		o.MinFontSize = DefaultOptions.MinFontSize
+	}
+	if o.MaxFontSize <= 0 {
+		o.MaxFontSize = DefaultOptions.MaxFontSize
+	}
+	if o.MaxFontSize < o.MinFontSize {
+		o.MaxFontSize = o.MinFontSize
+	}
+	if o.MaxWords <= 0 {
+		o.MaxWords = DefaultOptions.MaxWords
+	}
+	return o
+}
+
+const (
+	// charWidth is the advance of a character of the monospace font
+	// relative to the font size
+	charWidth = 0.6
+	// padding is the space kept around each word, in pixels
+	padding = 2
+	// spiralStep is the angle between two positions tried on the spiral
+	spiralStep = 0.1
+	// spiralSpacing is the distance between two turns of the spiral, in
+	// pixels
+	spiralSpacing = 4
+)
+
+// Box is a rectangle with its top left corner at X, Y
+type Box struct {
+	X, Y, Width, Height float64
+}
+
+func (b Box) overlaps(o Box) bool {
+	return b.X < o.X+o.Width && o.X < b.X+b.Width &&
+		b.Y < o.Y+o.Height && o.Y < b.Y+b.Height
+}
+
+// Placement is a word placed in the cloud
+type Placement struct {
+	Word
+	FontSize float64
+	// Box bounds the word
+	Box
+}
+
+// Layout places the heaviest words on an Archimedean spiral starting from the
+// center of the image, moving each word outwards until it overlaps no word
+// placed before it. Words that don't fit in the image are left out.
+func Layout(words []Word, opts Options) []Placement {
+	opts = opts.withDefaults()
+
+	sorted := make([]Word, len(words))
+	copy(sorted, words)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Weight > sorted[j].Weight })
+	if len(sorted) > opts.MaxWords {
+		sorted = sorted[:opts.MaxWords]
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	minWeight, maxWeight := sorted[len(sorted)-1].Weight, sorted[0].Weight
+	fontSize := func(weight int64) float64 {
+		if maxWeight == minWeight {
+			return opts.MaxFontSize
+		}
+		ratio := float64(weight-minWeight) / float64(maxWeight-minWeight)
+		return opts.MinFontSize + ratio*(opts.MaxFontSize-opts.MinFontSize)
+	}
+
+	var (
+		width, height = float64(opts.Width), float64(opts.Height)
+		cx, cy        = width / 2, height / 2
+		// the spiral is stretched to the shape of the image
+		aspect = width / height
+		xs, ys = math.Max(aspect, 1), 1 / math.Min(aspect, 1)
+		// beyond this radius the spiral is out of the image
+		maxRadius = math.Hypot(cx/xs, cy/ys)
+		placed    []Placement
+	)
+	for _, w := range sorted {
+		size := fontSize(w.Weight)
+		box := Box{
+			Width:  float64(utf8.RuneCountInString(w.Text))*size*charWidth + 2*padding,
+			Height: size + 2*padding,
+		}
+		if box.Width > width || box.Height > height {
+			continue
+		}
+
+		for t := 0.0; spiralSpacing*t/(2*math.Pi) <= maxRadius; t += spiralStep {
+			r := spiralSpacing * t / (2 * math.Pi)
+			box.X = cx + r*math.Cos(t)*xs - box.Width/2
+			box.Y = cy + r*math.Sin(t)*ys - box.Height/2
+
+			if fits(box, width, height, placed) {
+				placed = append(placed, Placement{Word: w, FontSize: size, Box: box})
+				break
+			}
+		}
+	}
+
+	return placed
+}
+
+// fits reports whether the box is within the image and overlaps none of the
+// placed words
+func fits(box Box, width, height float64, placed []Placement) bool {
+	if box.X < 0 || box.Y < 0 || box.X+box.Width > width || box.Y+box.Height > height {
+		return false
+	}
+	for _, p := range placed {
+		if box.overlaps(p.Box) {
+			return false
+		}
+	}
+	return true
+}
+
+// palette are the colors of the words, in turn
+var palette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+// WriteSVG lays out the words and writes the word cloud as an SVG image
+func WriteSVG(w io.Writer, words []Word, opts Options) error {
+	opts = opts.withDefaults()
+	placed := Layout(words, opts)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+
+	for i, p := range placed {
+		var link string
+		if opts.Link != nil {
+			link = opts.Link(p.Word)
+		}
+		if link != "" {
+			bw.WriteString(`<a href="`)
+			xml.EscapeText(bw, []byte(link))
+			bw.WriteString(`">`)
+		}
+
+		// the baseline is about a fifth of the font size above the bottom
+		// of the text
+		fmt.Fprintf(bw, `<text x="%.1f" y="%.1f" font-size="%.1f" fill="%s"><title>%d</title>`,
+			p.X+padding, p.Y+padding+0.8*p.FontSize, p.FontSize, palette[i%len(palette)], p.Weight)
+		xml.EscapeText(bw, []byte(p.Text))
+		bw.WriteString(`</text>`)
+
+		if link != "" {
+			bw.WriteString(`</a>`)
+		}
+		bw.WriteString("\n")
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,96 @@
+package wordcloud_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/wordcloud"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	var words []wordcloud.Word
+	for i := 1; i <= 60; i++ {
+		words = append(words, wordcloud.Word{Text: fmt.Sprintf("term%d", i), Weight: int64(i)})
+	}
+
+	opts := wordcloud.Options{Width: 600, Height: 400, MinFontSize: 10, MaxFontSize: 40, MaxWords: 50}
+	placed := wordcloud.Layout(words, opts)
+	require.Len(t, placed, 50)
+
+	// the heaviest words are placed first, in the largest font
+	require.Equal(t, "term60", placed[0].Text)
+	require.Equal(t, 40.0, placed[0].FontSize)
+	require.Equal(t, 10.0, placed[len(placed)-1].FontSize)
+	for i := 1; i < len(placed); i++ {
+		require.LessOrEqual(t, placed[i].FontSize, placed[i-1].FontSize)
+	}
+
+	// the heaviest word is centered
+	require.InDelta(t, 300, placed[0].X+placed[0].Width/2, 0.01)
+	require.InDelta(t, 200, placed[0].Y+placed[0].Height/2, 0.01)
+
+	for i, p := range placed {
+		require.GreaterOrEqual(t, p.X, 0.0)
+		require.GreaterOrEqual(t, p.Y, 0.0)
+		require.LessOrEqual(t, p.X+p.Width, 600.0)
+		require.LessOrEqual(t, p.Y+p.Height, 400.0)
+
+		for _, o := range placed[:i] {
+			overlaps := p.X < o.X+o.Width && o.X < p.X+p.Width &&
+				p.Y < o.Y+o.Height && o.Y < p.Y+p.Height
+			require.False(t, overlaps, "%q overlaps %q", p.Text, o.Text)
+		}
+	}
+
+	// words too large for the image are left out
+	placed = wordcloud.Layout([]wordcloud.Word{
+		{Text: strings.Repeat("x", 100), Weight: 2},
+		{Text: "small", Weight: 1},
+	}, opts)
+	require.Len(t, placed, 1)
+	require.Equal(t, "small", placed[0].Text)
+
+	require.Empty(t, wordcloud.Layout(nil, opts))
+}
+
+func TestWriteSVG(t *testing.T) {
+	words := []wordcloud.Word{
+		{Text: "kubernetes", Weight: 5},
+		{Text: "load balancer", Weight: 3},
+		{Text: "<script>", Weight: 1},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, wordcloud.WriteSVG(buf, words, wordcloud.Options{
+		Link: func(w wordcloud.Word) string {
+			return "/notes?q=" + url.QueryEscape(w.Text) + "&x=1"
+		},
+	}))
+
+	var svg struct {
+		XMLName xml.Name `xml:"svg"`
+		Width   int      `xml:"width,attr"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Text string `xml:"text"`
+		} `xml:"a"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &svg))
+	require.Equal(t, wordcloud.DefaultOptions.Width, svg.Width)
+	require.Len(t, svg.Links, 3)
+	require.Equal(t, "/notes?q=kubernetes&x=1", svg.Links[0].Href)
+	require.Equal(t, "kubernetes", svg.Links[0].Text)
+	require.Equal(t, "/notes?q=load+balancer&x=1", svg.Links[1].Href)
+	require.Equal(t, "<script>", svg.Links[2].Text)
+
+	// without links, words are plain text
+	buf.Reset()
+	require.NoError(t, wordcloud.WriteSVG(buf, words, wordcloud.Options{}))
+	require.NotContains(t, buf.String(), "<a ")
+	require.Equal(t, 3, strings.Count(buf.String(), "<text "))
+}
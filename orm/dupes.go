@@ -0,0 +1,326 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// shingleSize is the number of consecutive words in a shingle
+	shingleSize = 3
+	// minHashes is the number of hash functions of a MinHash signature. The
+	// error of the estimated similarity is about 1/sqrt(minHashes).
+	minHashes = 128
+)
+
+// DuplicateGroup is a group of notes holding the same or nearly the same
+// text
+type DuplicateGroup struct {
+	// Notes are the current revisions of the notes, lowest ID first
+	Notes []NoteRev
+	// Exact is true when all notes share the same blob
+	Exact bool
+	// Similarity is the lowest estimated Jaccard similarity of the word
+	// shingles of two notes linking the group, 1 for exact duplicates
+	Similarity float64
+}
+
+// minHashSeeds are the multipliers and increments of the hash functions of
+// MinHash signatures
+var minHashSeeds = func() [minHashes][2]uint64 {
+	var seeds [minHashes][2]uint64
+	state := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for i := range seeds {
+		seeds[i] = [2]uint64{next() | 1, next()}
+	}
+	return seeds
+}()
+
+// minHash is the MinHash signature of the shingles of a text
+type minHash [minHashes]uint64
+
+// newMinHash computes the signature of the shingles of consecutive words of
+// the text, case insensitive. It returns false when the text has no words.
+func newMinHash(text string) (minHash, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return minHash{}, false
+	}
+
+	var mh minHash
+	for i := range mh {
+		mh[i] = math.MaxUint64
+	}
+
+	// texts shorter than a shingle are a single shingle
+	for i := 0; i == 0 || i+shingleSize <= len(words); i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		x := h.Sum64()
+
+		for j, seed := range minHashSeeds {
+			if v := seed[0]*x + seed[1]; v < mh[j] {
+				mh[j] = v
+			}
+		}
+	}
+
+	return mh, true
+}
+
+// similarity estimates the Jaccard similarity of the shingles of two texts
+func (mh minHash) similarity(other minHash) float64 {
+	same := 0
+	for i := range mh {
+		if mh[i] == other[i] {
+			same++
+		}
+	}
+	return float64(same) / minHashes
+}
+
+// FindDuplicates groups the notes that share the same current blob, then the
+// notes whose text is nearly the same, with an estimated similarity of at
+// least threshold. Notes with the same blob are compared once, so a near
+// duplicate group lists a single note of an exact group. Trashed notes are
+// left out.
+func (r Repo) FindDuplicates(ctx context.Context, threshold float64) ([]DuplicateGroup, error) {
+	notes, err := r.GetNotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(ByID{notes})
+
+	var (
+		groups   []DuplicateGroup
+		bySHA    = map[string][]NoteRev{}
+		distinct []NoteRev
+	)
+	for _, n := range notes {
+		if len(bySHA[n.SHA256]) == 0 {
+			distinct = append(distinct, n)
+		}
+		bySHA[n.SHA256] = append(bySHA[n.SHA256], n)
+	}
+	for _, n := range distinct {
+		if same := bySHA[n.SHA256]; len(same) > 1 {
+			groups = append(groups, DuplicateGroup{Notes: same, Exact: true, Similarity: 1})
+		}
+	}
+
+	var (
+		revs   []NoteRev
+		hashes []minHash
+	)
+	for _, n := range distinct {
+		body, err := readBody(ctx, r, n)
+		if err != nil {
+			return nil, err
+		}
+		if mh, ok := newMinHash(body); ok {
+			revs = append(revs, n)
+			hashes = append(hashes, mh)
+		}
+	}
+
+	// link similar notes into groups with a union-find
+	parent := make([]int, len(revs))
+	lowest := make([]float64, len(revs))
+	for i := range parent {
+		parent[i] = i
+		lowest[i] = 1
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			sim := hashes[i].similarity(hashes[j])
+			if sim < threshold {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			if ri != rj {
+				if lowest[rj] < lowest[ri] {
+					lowest[ri] = lowest[rj]
+				}
+				parent[rj] = ri
+			}
+			if sim < lowest[ri] {
+				lowest[ri] = sim
+			}
+		}
+	}
+
+	members := map[int][]NoteRev{}
+	var roots []int
+	for i, n := range revs {
+		root := find(i)
+		if len(members[root]) == 0 {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], n)
+	}
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, DuplicateGroup{Notes: members[root], Similarity: lowest[root]})
+		}
+	}
+
+	return groups, nil
+}
+
+// MergeNotes combines the body of the note with the ID from into the note
+// with the ID into and trashes the note from. The lines of from that are
+// not in into are inserted where from has them, following a line diff of
+// both notes. Both notes keep their revisions, so the merge can be undone by
+// restoring from.
+func (r Repo) MergeNotes(ctx context.Context, into, from int64) (NoteRev, error) {
+	if into == from {
+		return NoteRev{}, fmt.Errorf("merging note %d into itself", into)
+	}
+
+	intoRev, err := r.GetCurrentNoteRev(ctx, into)
+	if err != nil {
+		return NoteRev{}, err
+	}
+	fromRev, err := r.GetCurrentNoteRev(ctx, from)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	return r.MergeNoteRevs(ctx, intoRev, fromRev)
+}
+
+// MergeNoteRevs merges the revision from into the revision into like
+// MergeNotes. It returns ErrConflict when either revision isn't the current
+// revision of its note anymore, so that a concurrent edit of either note
+// isn't overwritten or trashed.
+func (r Repo) MergeNoteRevs(ctx context.Context, into, from NoteRev) (NoteRev, error) {
+	if into.ID == from.ID {
+		return NoteRev{}, fmt.Errorf("merging note %d into itself", into.ID)
+	}
+
+	intoBody, err := readBody(ctx, r, into)
+	if err != nil {
+		return NoteRev{}, err
+	}
+	fromBody, err := readBody(ctx, r, from)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("starting merge notes tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, nr := range []NoteRev{into, from} {
+		if err := checkCurrentTx(ctx, tx, nr); err != nil {
+			return NoteRev{}, err
+		}
+	}
+
+	merged := mergeBodies(intoBody, fromBody)
+	newRev := into
+	if merged != intoBody {
+		if err := restoreNoteTx(ctx, tx, into.ID); err != nil {
+			return NoteRev{}, err
+		}
+		if newRev, err = updateBlobTx(ctx, tx, into.ID, into.SHA256, strings.NewReader(merged)); err != nil {
+			return NoteRev{}, err
+		}
+	}
+
+	if err := trashNoteTx(ctx, tx, from.ID); err != nil {
+		return NoteRev{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NoteRev{}, fmt.Errorf("commiting merge notes tx: %w", err)
+	}
+
+	return newRev, nil
+}
+
+// mergeBodies merges the lines of from into into along a line diff of both
+// bodies. Lines of from that into lacks are inserted where from has them,
+// after the lines of into they differ from, so that no line of either body
+// is lost and blocks such as code fences keep their lines in order.
+func mergeBodies(into, from string) string {
+	intoLines, fromLines := bodyLines(into), bodyLines(from)
+
+	var (
+		merged []string
+		added  bool
+	)
+	m := difflib.NewMatcherWithJunk(intoLines, fromLines, false, nil)
+	for _, op := range m.GetOpCodes() {
+		merged = append(merged, intoLines[op.I1:op.I2]...)
+		if op.Tag == 'i' || op.Tag == 'r' {
+			merged = append(merged, fromLines[op.J1:op.J2]...)
+			added = true
+		}
+	}
+	if !added {
+		return into
+	}
+
+	return strings.Join(merged, "")
+}
+
+// bodyLines splits a body into lines ending with a newline
+func bodyLines(body string) []string {
+	if body == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(body, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
+
+func readBody(ctx context.Context, r Repo, nr NoteRev) (string, error) {
+	body, err := nr.GetReader(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("getting body of note %d: %w", nr.ID, err)
+	}
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("reading body of note %d: %w", nr.ID, err)
+	}
+	return string(raw), nil
+}
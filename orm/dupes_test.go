@@ -0,0 +1,226 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Deploy\nbuild the image, push it to the registry, roll out the deployment and watch the pods come up one by one",
+		"grocery list: milk, eggs and bread",
+		"# Deploy\nbuild the image, push it to the registry, roll out the deployment and watch the pods come up one by one slowly",
+		"grocery list: milk, eggs and bread",
+		"",
+	})
+
+	groups, err := repo.FindDuplicates(ctx, 0.8)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	require.True(t, groups[0].Exact)
+	require.Equal(t, []orm.NoteRev{revs[1], revs[3]}, groups[0].Notes)
+	require.Equal(t, 1.0, groups[0].Similarity)
+
+	require.False(t, groups[1].Exact)
+	require.Equal(t, []orm.NoteRev{revs[0], revs[2]}, groups[1].Notes)
+	require.InDelta(t, 0.9, groups[1].Similarity, 0.1)
+
+	// a tighter threshold only keeps the exact duplicates
+	groups, err = repo.FindDuplicates(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.True(t, groups[0].Exact)
+}
+
+func TestMergeNotes(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Oncall\npage the primary\n",
+		"# Oncall\npage the primary\nthen page the secondary\n",
+	})
+
+	merged, err := repo.MergeNotes(ctx, revs[0].ID, revs[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, revs[0].ID, merged.ID)
+
+	body, err := merged.GetReader(ctx, repo)
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "# Oncall\npage the primary\nthen page the secondary\n", string(raw))
+
+	// both histories are kept and the redundant note is trashed
+	intoRevs, err := repo.GetNoteRevs(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, intoRevs, 2)
+	fromRevs, err := repo.GetNoteRevs(ctx, revs[1].ID)
+	require.NoError(t, err)
+	require.Len(t, fromRevs, 1)
+
+	notes, err := repo.GetNotes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []orm.NoteRev{merged}, notes)
+
+	trashed, err := repo.GetTrashedNotes(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	require.Equal(t, revs[1], trashed[0].NoteRev)
+
+	results, err := repo.FullTextSearch(ctx, "secondary")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, merged, mustGetNoteRev(t, ctx, repo, results[0]))
+
+	_, err = repo.MergeNotes(ctx, revs[0].ID, revs[0].ID)
+	require.Error(t, err)
+	_, err = repo.MergeNotes(ctx, revs[0].ID, 99)
+	require.ErrorIs(t, err, orm.ErrNotFound)
+}
+
+func TestMergeNoteRevsConflict(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Oncall\npage the primary\n",
+		"# Oncall\npage the primary\nthen page the secondary\n",
+	})
+
+	// the note merged into was edited since it was read
+	edited, err := revs[0].UpdateBlob(ctx, repo, bytes.NewBufferString("# Oncall\npage the manager\n"))
+	require.NoError(t, err)
+	_, err = repo.MergeNoteRevs(ctx, revs[0], revs[1])
+	require.ErrorIs(t, err, orm.ErrConflict)
+
+	// the note merged from was edited since it was read
+	_, err = revs[1].UpdateBlob(ctx, repo, bytes.NewBufferString("# Oncall\npage everyone\n"))
+	require.NoError(t, err)
+	_, err = repo.MergeNoteRevs(ctx, edited, revs[1])
+	require.ErrorIs(t, err, orm.ErrConflict)
+
+	// neither note was changed or trashed
+	intoRevs, err := repo.GetNoteRevs(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, intoRevs, 2)
+	trashed, err := repo.GetTrashedNotes(ctx)
+	require.NoError(t, err)
+	require.Empty(t, trashed)
+}
+
+func TestMergeNotesMarkdown(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// blank lines, fences, rules and repeated list items are kept where
+	// each note has them
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Deploy\n\nBuild the image:\n\n```sh\nmake image\n```\n\n---\n\n- [ ] check\n",
+		"# Deploy\n\nBuild the image:\n\n```sh\nmake image\nmake push\n```\n\nRoll it out:\n\n```sh\nkubectl rollout restart\n```\n\n- [ ] check\n- [ ] check\n",
+	})
+
+	merged, err := repo.MergeNotes(ctx, revs[0].ID, revs[1].ID)
+	require.NoError(t, err)
+
+	body, err := merged.GetReader(ctx, repo)
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "# Deploy\n\nBuild the image:\n\n```sh\nmake image\nmake push\n```\n\n"+
+		"---\n"+
+		"Roll it out:\n\n```sh\nkubectl rollout restart\n```\n\n"+
+		"- [ ] check\n- [ ] check\n", string(raw))
+}
+
+func TestTrashNote(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"k8s cluster",
+		"k8s upgrade",
+	})
+
+	search := func() []int64 {
+		t.Helper()
+		results, err := repo.FullTextSearch(ctx, "k8s")
+		require.NoError(t, err)
+		ids := []int64{}
+		for _, r := range results {
+			ids = append(ids, mustGetNoteRev(t, ctx, repo, r).ID)
+		}
+		return ids
+	}
+
+	require.NoError(t, repo.TrashNote(ctx, revs[0].ID))
+	require.Equal(t, []int64{revs[1].ID}, search())
+
+	isTrashed, err := repo.IsTrashed(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.True(t, isTrashed)
+
+	notes, err := repo.GetNotes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []orm.NoteRev{revs[1]}, notes)
+
+	require.NoError(t, repo.RestoreNote(ctx, revs[0].ID))
+	isTrashed, err = repo.IsTrashed(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.False(t, isTrashed)
+	require.ElementsMatch(t, []int64{revs[0].ID, revs[1].ID}, search())
+	require.ErrorIs(t, repo.RestoreNote(ctx, revs[0].ID), orm.ErrNotFound)
+	require.ErrorIs(t, repo.TrashNote(ctx, 99), orm.ErrNotFound)
+
+	// editing a trashed note restores it
+	require.NoError(t, repo.TrashNote(ctx, revs[1].ID))
+	_, err = revs[1].UpdateBlob(ctx, repo, bytes.NewBufferString("k8s upgrade done"))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{revs[0].ID, revs[1].ID}, search())
+
+	trashed, err := repo.GetTrashedNotes(ctx)
+	require.NoError(t, err)
+	require.Empty(t, trashed)
+}
+
+func mustGetNoteRev(t *testing.T, ctx context.Context, repo orm.Repo, result orm.FTSResult) orm.NoteRev {
+	t.Helper()
+	nr, err := result.GetNoteRev(ctx, repo)
+	require.NoError(t, err)
+	return nr
+}
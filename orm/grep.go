@@ -0,0 +1,123 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	sqlite "github.com/mattn/go-sqlite3"
+)
+
+// driverName is the database/sql driver of nests, SQLite with a REGEXP
+// function so that queries can use "X REGEXP Y"
+const driverName = "sqlite3_nestable"
+
+func init() {
+	sql.Register(driverName, &sqlite.SQLiteDriver{
+		ConnectHook: func(conn *sqlite.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", regexpMatch, true)
+		},
+	})
+}
+
+// regexps caches the compiled patterns of the REGEXP function, which is
+// called for every row
+var regexps sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(pattern, re)
+	return re, nil
+}
+
+// regexpMatch implements the REGEXP function with Go regular expressions.
+// SQLite calls it with the pattern first for "X REGEXP Y".
+func regexpMatch(pattern string, s []byte) (bool, error) {
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.Match(s), nil
+}
+
+// GrepMatch is a line of a note revision matching a regular expression
+type GrepMatch struct {
+	NoteRev
+	// Line is the number of the matching line, starting at 1
+	Line int
+	Text string
+}
+
+// GrepOptions changes which note revisions are scanned by Grep
+type GrepOptions struct {
+	// History scans every revision of the notes instead of only the
+	// current ones
+	History bool
+}
+
+// Grep returns the lines of the current note revisions matching the Go
+// regular expression, ordered by note ID and line. Unlike a full text
+// search, the pattern can match any part of a line, such as punctuation or
+// partial words. Trashed notes are left out.
+func (r Repo) Grep(ctx context.Context, pattern string, opts GrepOptions) ([]GrepMatch, error) {
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, QuerySyntaxError{Query: pattern, Reason: err.Error()}
+	}
+
+	revs := `SELECT MAX(rowid) FROM note_rev GROUP BY note_id`
+	if opts.History {
+		revs = `SELECT rowid FROM note_rev`
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT nr.note_id, nr.blob_sha256, nr.timestamp, blob.body
+		FROM note_rev AS nr
+		INNER JOIN blob ON blob.sha256 = nr.blob_sha256
+		WHERE nr.rowid IN (%s)
+		AND nr.note_id NOT IN (SELECT note_id FROM note_trash)
+		AND blob.body REGEXP (?)
+		ORDER BY nr.note_id, nr.rowid DESC`, revs),
+		// ^ and $ match at line boundaries like in the lines matched below
+		"(?m)"+pattern)
+	if err != nil {
+		return nil, fmt.Errorf("grepping notes: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []GrepMatch
+	for rows.Next() {
+		var (
+			nr   NoteRev
+			body []byte
+		)
+		if err := rows.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp, &body); err != nil {
+			return nil, fmt.Errorf("scanning grep results: %w", err)
+		}
+		nr.Timestamp = nr.Timestamp.Local()
+
+		for i, line := range strings.Split(string(body), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if re.MatchString(line) {
+				matches = append(matches, GrepMatch{NoteRev: nr, Line: i + 1, Text: line})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("grepping notes: %w", err)
+	}
+
+	return matches, nil
+}
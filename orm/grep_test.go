@@ -0,0 +1,65 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrep(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Net\nrouter 10.0.3.1\nswitch 10.0.4.1\nproxy 10.0.3.254",
+		"# Errors\nerr_code=503 upstream\nerr_code=404 missing",
+		"# Trashed\n10.0.3.9",
+	})
+	require.NoError(t, repo.TrashNote(ctx, revs[2].ID))
+
+	grep := func(pattern string, opts orm.GrepOptions) []orm.GrepMatch {
+		t.Helper()
+		matches, err := repo.Grep(ctx, pattern, opts)
+		require.NoError(t, err)
+		return matches
+	}
+
+	require.Equal(t, []orm.GrepMatch{
+		{NoteRev: revs[0], Line: 2, Text: "router 10.0.3.1"},
+		{NoteRev: revs[0], Line: 4, Text: "proxy 10.0.3.254"},
+	}, grep(`10\.0\.3\.\d+`, orm.GrepOptions{}))
+
+	require.Equal(t, []orm.GrepMatch{
+		{NoteRev: revs[1], Line: 2, Text: "err_code=503 upstream"},
+	}, grep(`err_code=5\d\d`, orm.GrepOptions{}))
+
+	// anchors match at the start and end of lines
+	require.Equal(t, []orm.GrepMatch{
+		{NoteRev: revs[0], Line: 3, Text: "switch 10.0.4.1"},
+	}, grep(`4\.1$`, orm.GrepOptions{}))
+
+	require.Empty(t, grep(`(?i)ERR_CODE=2`, orm.GrepOptions{}))
+	require.Len(t, grep(`(?i)ERR_CODE`, orm.GrepOptions{}), 2)
+
+	// removed lines are only found in the history
+	updated, err := revs[1].UpdateBlob(ctx, repo, bytes.NewBufferString("# Errors\nerr_code=404 missing"))
+	require.NoError(t, err)
+	require.Empty(t, grep(`err_code=5`, orm.GrepOptions{}))
+	require.Equal(t, []orm.GrepMatch{
+		{NoteRev: updated, Line: 2, Text: "err_code=404 missing"},
+		{NoteRev: revs[1], Line: 2, Text: "err_code=503 upstream"},
+		{NoteRev: revs[1], Line: 3, Text: "err_code=404 missing"},
+	}, grep(`err_code`, orm.GrepOptions{History: true}))
+
+	_, err = repo.Grep(ctx, `10.0.3.(`, orm.GrepOptions{})
+	require.ErrorIs(t, err, orm.ErrBadQuery)
+}
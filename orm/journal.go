@@ -0,0 +1,95 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DayLayout is the layout of the date keys for journal notes
+const DayLayout = "2006-01-02"
+
+// DayKey returns the journal key for the local day of the time
+func DayKey(t time.Time) string { return t.Local().Format(DayLayout) }
+
+// JournalDay is the note kept for a calendar day
+type JournalDay struct {
+	Day string
+	NoteRev
+}
+
+// GetJournalNote returns the current revision of the note for the day
+func (r Repo) GetJournalNote(ctx context.Context, day string) (NoteRev, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT note_id FROM journal WHERE day = (?)", day)
+
+	var id int64
+	if err := row.Scan(&id); errors.Is(err, sql.ErrNoRows) {
+		return NoteRev{}, fmt.Errorf("journal note for %s: %w", day, ErrNotFound)
+	} else if err != nil {
+		return NoteRev{}, fmt.Errorf("querying journal note for %s: %w", day, err)
+	}
+
+	return r.GetCurrentNoteRev(ctx, id)
+}
+
+// NewJournalNote creates the note for the day
+func (r Repo) NewJournalNote(ctx context.Context, day string, src io.Reader) (NoteRev, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("starting new journal note tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	nr, err := newNoteTx(ctx, tx, src)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO journal (day, note_id) VALUES (?, ?)", day, nr.ID)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("inserting journal day %s: %w", day, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NoteRev{}, fmt.Errorf("commiting new journal note tx: %w", err)
+	}
+
+	return nr, nil
+}
+
+// JournalDays returns the current revisions of all journal notes, most
+// recent day first
+func (r Repo) JournalDays(ctx context.Context) ([]JournalDay, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT journal.day, note_rev.note_id, note_rev.blob_sha256, note_rev.timestamp, MAX(note_rev.rowid)
+		FROM journal
+		INNER JOIN note_rev
+			ON journal.note_id = note_rev.note_id
+		GROUP BY journal.day
+		ORDER BY journal.day DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("querying journal days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []JournalDay
+
+	for rows.Next() {
+		var (
+			jd    JournalDay
+			rowid int64
+		)
+		if err := rows.Scan(&jd.Day, &jd.ID, &jd.SHA256, &jd.Timestamp, &rowid); err != nil {
+			return nil, fmt.Errorf("scanning journal days: %w", err)
+		}
+		jd.Timestamp = jd.Timestamp.Local()
+		days = append(days, jd)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,65 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	day := orm.DayKey(orm.Now())
+	_, err := repo.GetJournalNote(ctx, day)
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	tmpl, err := repo.GetConfig(ctx, orm.ConfigJournalTmpl)
+	require.NoError(t, err)
+
+	data := orm.NewTemplateData()
+	body := &bytes.Buffer{}
+	require.NoError(t, orm.RenderTemplate(body, tmpl, data))
+
+	now := time.Unix(2, 0).Local()
+	require.Equal(t, "# "+now.Weekday().String()+", "+now.Format(orm.DayLayout)+"\n\n", body.String())
+
+	created, err := repo.NewJournalNote(ctx, day, body)
+	require.NoError(t, err)
+
+	// a day only has one note
+	_, err = repo.NewJournalNote(ctx, day, bytes.NewBufferString("again"))
+	require.Error(t, err)
+
+	found, err := repo.GetJournalNote(ctx, day)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, found.ID)
+	require.Equal(t, created.SHA256, found.SHA256)
+
+	updated, err := found.AppendBlob(ctx, repo, bytes.NewBufferString("- wrote a test"))
+	require.NoError(t, err)
+
+	// regular notes are not journal days
+	ormtest.InsertTestNotes(t, ctx, repo, []string{"not a journal"})
+
+	yesterday, err := repo.NewJournalNote(ctx, "1969-12-01", bytes.NewBufferString("# yesterday"))
+	require.NoError(t, err)
+
+	days, err := repo.JournalDays(ctx)
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	require.Equal(t, day, days[0].Day)
+	require.Equal(t, updated.SHA256, days[0].SHA256)
+	require.Equal(t, "1969-12-01", days[1].Day)
+	require.Equal(t, yesterday.ID, days[1].ID)
+}
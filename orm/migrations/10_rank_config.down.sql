@@ -0,0 +1 @@
+DELETE FROM config WHERE key IN ("rank_recency_weight", "rank_half_life", "rank_title_boost");
\ No newline at end of file
@@ -0,0 +1,4 @@
+INSERT INTO config (key, value, description) VALUES
+	("rank_recency_weight", "1", "boost of the search score of recently modified notes, 0 ranks by BM25 only"),
+	("rank_half_life", "30", "days after which the recency boost of a note in search results halves"),
+	("rank_title_boost", "2", "multiplier of the search score of notes matching in their first line");
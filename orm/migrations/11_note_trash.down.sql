@@ -0,0 +1,5 @@
+-- restores the FTS entries of trashed notes
+DELETE FROM note_trash;
+DROP TRIGGER IF EXISTS restore_note_fts;
+DROP TRIGGER IF EXISTS trash_note_fts;
+DROP TABLE IF EXISTS note_trash;
\ No newline at end of file
@@ -0,0 +1,31 @@
+/* note_trash holds notes that were thrown away, e.g. when merged into a
+duplicate. Trashed notes keep their revisions so they can be restored. */
+CREATE TABLE note_trash (
+	note_id INTEGER PRIMARY KEY,
+	trashed_at DATETIME NOT NULL,
+
+	FOREIGN KEY (note_id) REFERENCES note (id)
+);
+
+-- removes the FTS entry of a trashed note so that it isn't found anymore
+CREATE TRIGGER trash_note_fts AFTER INSERT ON note_trash BEGIN
+	DELETE FROM note_fts
+	WHERE note_rev_rowid = (
+		SELECT MAX(rowid)
+		FROM note_rev
+		WHERE note_id = new.note_id
+	);
+END;
+
+-- inserts the FTS entry of the current revision of a restored note
+CREATE TRIGGER restore_note_fts AFTER DELETE ON note_trash BEGIN
+	INSERT INTO note_fts(note_rev_rowid, blob_sha256, blob_body)
+		SELECT nr.rowid, blob.sha256, blob.body
+		FROM note_rev AS nr
+		INNER JOIN blob ON nr.blob_sha256 = blob.sha256
+		WHERE nr.rowid = (
+			SELECT MAX(rowid)
+			FROM note_rev
+			WHERE note_id = old.note_id
+		);
+END;
@@ -0,0 +1,3 @@
+DELETE FROM config WHERE key IN ("wordcloud_min_length", "wordcloud_numbers");
+DELETE FROM stop_words;
+DROP INDEX IF EXISTS stop_words_word;
\ No newline at end of file
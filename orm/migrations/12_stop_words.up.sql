@@ -0,0 +1,32 @@
+-- stop words are unique so that they can be added and removed one by one
+CREATE UNIQUE INDEX stop_words_word ON stop_words (word);
+
+-- common English words that carry no meaning on their own, including the
+-- fragments of contractions split by the FTS tokenizer, e.g. "don't"
+INSERT OR IGNORE INTO stop_words (word) VALUES
+	("a"), ("about"), ("above"), ("after"), ("again"), ("against"),
+	("all"), ("am"), ("an"), ("and"), ("any"), ("are"), ("aren"), ("as"),
+	("at"), ("be"), ("because"), ("been"), ("before"), ("being"),
+	("below"), ("between"), ("both"), ("but"), ("by"), ("can"), ("cannot"),
+	("could"), ("couldn"), ("d"), ("did"), ("didn"), ("do"), ("does"),
+	("doesn"), ("doing"), ("don"), ("down"), ("during"), ("each"), ("few"),
+	("for"), ("from"), ("further"), ("had"), ("hadn"), ("has"), ("hasn"),
+	("have"), ("haven"), ("having"), ("he"), ("her"), ("here"), ("hers"),
+	("herself"), ("him"), ("himself"), ("his"), ("how"), ("i"), ("if"),
+	("in"), ("into"), ("is"), ("isn"), ("it"), ("its"), ("itself"),
+	("just"), ("ll"), ("m"), ("me"), ("more"), ("most"), ("my"),
+	("myself"), ("no"), ("nor"), ("not"), ("now"), ("of"), ("off"), ("on"),
+	("once"), ("only"), ("or"), ("other"), ("our"), ("ours"),
+	("ourselves"), ("out"), ("over"), ("own"), ("re"), ("s"), ("same"),
+	("shan"), ("she"), ("should"), ("shouldn"), ("so"), ("some"), ("such"),
+	("t"), ("than"), ("that"), ("the"), ("their"), ("theirs"), ("them"),
+	("themselves"), ("then"), ("there"), ("these"), ("they"), ("this"),
+	("those"), ("through"), ("to"), ("too"), ("under"), ("until"), ("up"),
+	("ve"), ("very"), ("was"), ("wasn"), ("we"), ("were"), ("weren"),
+	("what"), ("when"), ("where"), ("which"), ("while"), ("who"), ("whom"),
+	("why"), ("will"), ("with"), ("won"), ("would"), ("wouldn"), ("you"),
+	("your"), ("yours"), ("yourself"), ("yourselves");
+
+INSERT INTO config (key, value, description) VALUES
+	("wordcloud_min_length", "3", "minimum number of characters of word cloud terms"),
+	("wordcloud_numbers", "false", "include terms made only of digits in the word cloud");
@@ -0,0 +1 @@
+DELETE FROM config WHERE key = "editor_ext";
\ No newline at end of file
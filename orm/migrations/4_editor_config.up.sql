@@ -0,0 +1,2 @@
+INSERT INTO config (key, value, description) VALUES
+	("editor_ext", "md", "file extension of temp files opened in the editor");
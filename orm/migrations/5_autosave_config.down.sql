@@ -0,0 +1 @@
+DELETE FROM config WHERE key IN ("autosave", "autosave_squash");
\ No newline at end of file
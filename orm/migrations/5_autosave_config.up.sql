@@ -0,0 +1,3 @@
+INSERT INTO config (key, value, description) VALUES
+	("autosave", "true", "save a revision each time the external editor writes the note"),
+	("autosave_squash", "true", "squash autosaved revisions into one when the editor exits");
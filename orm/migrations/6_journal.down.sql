@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS journal;
+DELETE FROM config WHERE key = "journal_template";
\ No newline at end of file
@@ -0,0 +1,10 @@
+/* journal maps a calendar day to the note kept for it */
+CREATE TABLE journal (
+	day TEXT PRIMARY KEY,
+	note_id INTEGER NOT NULL UNIQUE,
+
+	FOREIGN KEY (note_id) REFERENCES note (id)
+);
+
+INSERT INTO config (key, value, description) VALUES
+	("journal_template", "# {{ .Weekday }}, {{ .Date }}" || char(10) || char(10), "template for new daily journal notes");
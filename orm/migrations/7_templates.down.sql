@@ -0,0 +1 @@
+DROP TABLE IF EXISTS template;
\ No newline at end of file
@@ -0,0 +1,5 @@
+/* template is a named skeleton for new notes */
+CREATE TABLE template (
+	name TEXT PRIMARY KEY,
+	body TEXT NOT NULL
+);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS insert_note_history_fts;
+DROP TRIGGER IF EXISTS remove_note_history_fts;
+DROP TRIGGER IF EXISTS enable_note_history_fts;
+DROP TRIGGER IF EXISTS disable_note_history_fts;
+DROP TABLE IF EXISTS note_history_fts;
+DELETE FROM config WHERE key = "history_index";
\ No newline at end of file
@@ -0,0 +1,45 @@
+-- Create virtual table for full text search of every note revision. Unlike
+-- note_fts, revisions are kept after they are superseded so that removed text
+-- can still be found. The index is opt-in with the history_index config.
+CREATE VIRTUAL TABLE note_history_fts
+	USING FTS5(
+		note_rev_rowid,
+		blob_sha256,
+		blob_body
+	);
+
+INSERT INTO config (key, value, description) VALUES
+	("history_index", "false", "index every revision of notes for `nst search -history`");
+
+-- inserts history FTS entry for new note revision while the index is enabled
+CREATE TRIGGER insert_note_history_fts AFTER INSERT ON note_rev
+WHEN (SELECT value FROM config WHERE key = "history_index") = "true"
+BEGIN
+	INSERT INTO note_history_fts(note_rev_rowid, blob_sha256, blob_body)
+		SELECT new.rowid, sha256, body
+		FROM blob
+		WHERE sha256 = new.blob_sha256;
+END;
+
+-- removes history FTS entries of deleted note revisions, e.g. when squashed
+CREATE TRIGGER remove_note_history_fts AFTER DELETE ON note_rev BEGIN
+	DELETE FROM note_history_fts
+	WHERE note_rev_rowid = old.rowid;
+END;
+
+-- mass inserts history FTS entries for all note revisions when enabled
+CREATE TRIGGER enable_note_history_fts AFTER UPDATE OF value ON config
+WHEN new.key = "history_index" AND new.value = "true" AND old.value != "true"
+BEGIN
+	INSERT INTO note_history_fts(note_rev_rowid, blob_sha256, blob_body)
+		SELECT nr.rowid, blob.sha256, blob.body
+		FROM note_rev AS nr
+		INNER JOIN blob ON nr.blob_sha256 = blob.sha256;
+END;
+
+-- empties the history FTS table when disabled
+CREATE TRIGGER disable_note_history_fts AFTER UPDATE OF value ON config
+WHEN new.key = "history_index" AND new.value != "true"
+BEGIN
+	DELETE FROM note_history_fts;
+END;
@@ -0,0 +1,7 @@
+/* saved_search is a named full text search query that is run again and
+again, like a smart folder */
+CREATE TABLE saved_search (
+	name TEXT PRIMARY KEY,
+	query TEXT NOT NULL,
+	last_run DATETIME
+);
@@ -15,18 +15,26 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
 	NestName = ".notebook.nest"
 )
 
+// ErrNotFound is returned when a requested record does not exist in the nest
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a note is updated from a revision that isn't
+// its current revision anymore
+var ErrConflict = errors.New("note was updated since")
+
 type Repo struct {
 	db *sql.DB
 }
@@ -38,6 +46,10 @@ var clock = time.Now
 // Intended to be used during tests to provide deterministic time.
 func SetClock(c func() time.Time) { clock = c }
 
+// Now returns the current time according to the clock used for note
+// timestamps
+func Now() time.Time { return clock() }
+
 func LoadRepo(dbPath string) (Repo, error) {
 	if dbPath == "" {
 		for _, p := range []string{
@@ -51,7 +63,7 @@ func LoadRepo(dbPath string) (Repo, error) {
 		}
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, dataSourceName(dbPath))
 	if err != nil {
 		return Repo{}, err
 	}
@@ -64,11 +76,23 @@ func LoadRepo(dbPath string) (Repo, error) {
 	return Repo{db}, nil
 }
 
+// dataSourceName returns the DSN of the nest at path, which may be a file:
+// URI with parameters. Transactions take the write lock when they begin, so
+// that concurrent edits wait for each other instead of failing to upgrade a
+// read lock with "database is locked".
+func dataSourceName(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_txlock=immediate"
+}
+
 //go:embed migrations/*.sql
 var migrationFS embed.FS
 
 func InitRepo(repoPath string) (Repo, error) {
-	db, err := sql.Open("sqlite3", repoPath)
+	db, err := sql.Open(driverName, dataSourceName(repoPath))
 	if err != nil {
 		return Repo{}, fmt.Errorf("opening DB for initialization: %w", err)
 	}
@@ -108,21 +132,64 @@ func (r Repo) MigrateUp() error {
 type ConfigKey string
 
 const (
-	ConfigEditor  ConfigKey = "editor"
-	ConfigVersion ConfigKey = "version"
+	ConfigEditor         ConfigKey = "editor"
+	ConfigEditorExt      ConfigKey = "editor_ext"
+	ConfigAutosave       ConfigKey = "autosave"
+	ConfigAutosaveSquash ConfigKey = "autosave_squash"
+	ConfigJournalTmpl    ConfigKey = "journal_template"
+	ConfigHistoryIndex   ConfigKey = "history_index"
+	ConfigRankRecency    ConfigKey = "rank_recency_weight"
+	ConfigRankHalfLife   ConfigKey = "rank_half_life"
+	ConfigRankTitleBoost ConfigKey = "rank_title_boost"
+	ConfigWCMinLength    ConfigKey = "wordcloud_min_length"
+	ConfigWCNumbers      ConfigKey = "wordcloud_numbers"
+	ConfigVersion        ConfigKey = "version"
 )
 
 func (r Repo) GetConfig(ctx context.Context, key ConfigKey) (string, error) {
 	row := r.db.QueryRowContext(ctx, "SELECT value FROM config WHERE key = (?)", key)
 
 	var value string
-	if err := row.Scan(&value); err != nil {
+	if err := row.Scan(&value); errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("config key %q: %w", key, ErrNotFound)
+	} else if err != nil {
 		return "", fmt.Errorf("getting config for key %q: %w", key, err)
 	}
 
 	return value, nil
 }
 
+// ConfigEntry is a config value with the description of its key
+type ConfigEntry struct {
+	Key         string
+	Value       string
+	Description string
+}
+
+// GetConfigEntries returns all config values ordered by key
+func (r Repo) GetConfigEntries(ctx context.Context) ([]ConfigEntry, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT key, value, description FROM config ORDER BY key")
+	if err != nil {
+		return nil, fmt.Errorf("getting config entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ConfigEntry
+	for rows.Next() {
+		var (
+			ce          ConfigEntry
+			description sql.NullString
+		)
+		if err := rows.Scan(&ce.Key, &ce.Value, &description); err != nil {
+			return nil, fmt.Errorf("config entry result: %w", err)
+		}
+		ce.Description = description.String
+		entries = append(entries, ce)
+	}
+
+	return entries, nil
+}
+
 func (r Repo) GetConfigKeys(ctx context.Context) ([]string, error) {
 	rows, err := r.db.QueryContext(ctx, "SELECT key FROM config")
 	if err != nil {
@@ -142,7 +209,39 @@ func (r Repo) GetConfigKeys(ctx context.Context) ([]string, error) {
 	return keys, nil
 }
 
+// boolConfigKeys are stored as "true" or "false" since triggers depend on
+// their exact value
+var boolConfigKeys = map[ConfigKey]bool{
+	ConfigAutosave:       true,
+	ConfigAutosaveSquash: true,
+	ConfigHistoryIndex:   true,
+	ConfigWCNumbers:      true,
+}
+
+// numberConfigKeys are stored as numbers that can't be negative
+var numberConfigKeys = map[ConfigKey]bool{
+	ConfigRankRecency:    true,
+	ConfigRankHalfLife:   true,
+	ConfigRankTitleBoost: true,
+	ConfigWCMinLength:    true,
+}
+
 func (r Repo) SetConfig(ctx context.Context, key ConfigKey, value string) error {
+	if boolConfigKeys[key] {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config key %q expects a boolean: %w", key, err)
+		}
+		value = strconv.FormatBool(b)
+	}
+	if numberConfigKeys[key] {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("config key %q expects a positive number, got %q", key, value)
+		}
+		value = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
 	_, err := r.db.ExecContext(ctx, "UPDATE CONFIG SET value = (?) WHERE key = (?)", value, key)
 	if err != nil {
 		return fmt.Errorf("setting config for key %q: %w", key, err)
@@ -175,7 +274,48 @@ type ByID struct{ Notes }
 
 func (bi ByID) Less(i, j int) bool { return bi.Notes[i].ID < bi.Notes[j].ID }
 
+// UpdateBlob creates a new revision of the note with the contents of src.
+// Updating a trashed note restores it.
 func (nr NoteRev) UpdateBlob(ctx context.Context, r Repo, src io.Reader) (NoteRev, error) {
+	return nr.updateBlob(ctx, r, "", src)
+}
+
+// UpdateBlobIfCurrent creates a new revision of the note with the contents
+// of src like UpdateBlob, only if nr is still the current revision of the
+// note. It returns ErrConflict otherwise. The revision is checked when the
+// new revision is inserted, so of concurrent updates from the same revision
+// only the first one succeeds.
+func (nr NoteRev) UpdateBlobIfCurrent(ctx context.Context, r Repo, src io.Reader) (NoteRev, error) {
+	return nr.updateBlob(ctx, r, nr.SHA256, src)
+}
+
+func (nr NoteRev) updateBlob(ctx context.Context, r Repo, base string, src io.Reader) (NoteRev, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("starting edit note tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := restoreNoteTx(ctx, tx, nr.ID); err != nil {
+		return NoteRev{}, err
+	}
+
+	newRev, err := updateBlobTx(ctx, tx, nr.ID, base, src)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NoteRev{}, fmt.Errorf("commiting new note tx: %w", err)
+	}
+
+	return newRev, nil
+}
+
+// updateBlobTx inserts a new revision of a note within a transaction. When
+// base isn't empty, the revision is only inserted if base is the blob SHA256
+// of the current revision of the note, and ErrConflict is returned otherwise.
+func updateBlobTx(ctx context.Context, tx *sql.Tx, id int64, base string, src io.Reader) (NoteRev, error) {
 	h := sha256.New()
 	src = io.TeeReader(src, h)
 
@@ -187,30 +327,46 @@ func (nr NoteRev) UpdateBlob(ctx context.Context, r Repo, src io.Reader) (NoteRe
 
 	sum := hex.EncodeToString(h.Sum(nil))
 
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return NoteRev{}, fmt.Errorf("starting edit note tx: %w", err)
-	}
-	defer tx.Rollback()
-
 	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO blob (body, sha256) VALUES (?, ?)", blob, sum)
 	if err != nil {
 		return NoteRev{}, fmt.Errorf("inserting new blob: %w", err)
 	}
 
 	timestamp := clock()
-	_, err = tx.ExecContext(ctx, "INSERT INTO note_rev(note_id, blob_sha256, timestamp) VALUES(?,?,?)", nr.ID, sum, timestamp.UTC())
-	if err != nil {
-		return NoteRev{}, fmt.Errorf("inserting new note rev: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return NoteRev{}, fmt.Errorf("commiting new note tx: %w", err)
+	if base == "" {
+		_, err = tx.ExecContext(ctx, "INSERT INTO note_rev(note_id, blob_sha256, timestamp) VALUES(?,?,?)", id, sum, timestamp.UTC())
+		if err != nil {
+			return NoteRev{}, fmt.Errorf("inserting new note rev: %w", err)
+		}
+	} else {
+		// the check and the insert are a single statement, so that no other
+		// revision can be inserted in between
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO note_rev(note_id, blob_sha256, timestamp)
+			SELECT ?, ?, ?
+			WHERE (
+				SELECT blob_sha256
+				FROM note_rev
+				WHERE note_id = (?)
+				ORDER BY rowid DESC
+				LIMIT 1
+			) = (?)`,
+			id, sum, timestamp.UTC(), id, base)
+		if err != nil {
+			return NoteRev{}, fmt.Errorf("inserting new note rev: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return NoteRev{}, fmt.Errorf("inserting new note rev: %w", err)
+		}
+		if n == 0 {
+			return NoteRev{}, fmt.Errorf("note %d from revision %s: %w", id, base, ErrConflict)
+		}
 	}
 
 	return NoteRev{
 		Note: Note{
-			nr.ID,
+			id,
 		},
 		Blob: Blob{
 			SHA256: sum,
@@ -219,6 +375,57 @@ func (nr NoteRev) UpdateBlob(ctx context.Context, r Repo, src io.Reader) (NoteRe
 	}, nil
 }
 
+// checkCurrentTx returns ErrConflict when nr isn't the current revision of
+// its note within a transaction
+func checkCurrentTx(ctx context.Context, tx *sql.Tx, nr NoteRev) error {
+	var sum string
+	err := tx.QueryRowContext(ctx,
+		`SELECT blob_sha256
+		FROM note_rev
+		WHERE note_id = (?)
+		ORDER BY rowid DESC
+		LIMIT 1`, nr.ID).Scan(&sum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("note %d: %w", nr.ID, ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("getting current revision of note %d: %w", nr.ID, err)
+	}
+
+	if sum != nr.SHA256 {
+		return fmt.Errorf("note %d from revision %s: %w", nr.ID, nr.SHA256, ErrConflict)
+	}
+	return nil
+}
+
+// AppendBlob creates a new revision of the note with the contents of src
+// added to the end of the current blob on a new line
+func (nr NoteRev) AppendBlob(ctx context.Context, r Repo, src io.Reader) (NoteRev, error) {
+	cur, err := nr.GetReader(ctx, r)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("reading current blob: %w", err)
+	}
+
+	body, err := ioutil.ReadAll(cur)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("reading current blob: %w", err)
+	}
+
+	entry, err := ioutil.ReadAll(src)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("reading appended blob: %w", err)
+	}
+
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		body = append(body, '\n')
+	}
+	body = append(body, entry...)
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		body = append(body, '\n')
+	}
+
+	return nr.UpdateBlob(ctx, r, bytes.NewReader(body))
+}
+
 // GetBlobHead returns an excerpt from the front of the blob limited by the specified length
 // from the provided repo
 // TODO: once streaming blob IO is available, change behavior so that head scans until the first
@@ -242,6 +449,26 @@ func (b Blob) GetReader(ctx context.Context, r Repo) (io.Reader, error) {
 }
 
 func (r Repo) NewNote(ctx context.Context, src io.Reader) (NoteRev, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return NoteRev{}, fmt.Errorf("starting new note tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	nr, err := newNoteTx(ctx, tx, src)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NoteRev{}, fmt.Errorf("commiting new note tx: %w", err)
+	}
+
+	return nr, nil
+}
+
+// newNoteTx inserts a new note and its first revision within a transaction
+func newNoteTx(ctx context.Context, tx *sql.Tx, src io.Reader) (NoteRev, error) {
 	h := sha256.New()
 	src = io.TeeReader(src, h)
 
@@ -253,12 +480,6 @@ func (r Repo) NewNote(ctx context.Context, src io.Reader) (NoteRev, error) {
 
 	sum := hex.EncodeToString(h.Sum(nil))
 
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return NoteRev{}, fmt.Errorf("starting new note tx: %w", err)
-	}
-	defer tx.Rollback()
-
 	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO blob (body, sha256) VALUES (?, ?)", blob, sum)
 	if err != nil {
 		return NoteRev{}, fmt.Errorf("inserting new blob: %w", err)
@@ -280,10 +501,6 @@ func (r Repo) NewNote(ctx context.Context, src io.Reader) (NoteRev, error) {
 		return NoteRev{}, fmt.Errorf("inserting new note rev: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return NoteRev{}, fmt.Errorf("commiting new note tx: %w", err)
-	}
-
 	return NoteRev{
 		Note: Note{
 			ID: noteID,
@@ -297,24 +514,126 @@ func (r Repo) NewNote(ctx context.Context, src io.Reader) (NoteRev, error) {
 
 func (r Repo) GetCurrentNoteRev(ctx context.Context, id int64) (NoteRev, error) {
 	row := r.db.QueryRowContext(ctx,
-		`SELECT note_id, blob_sha256, timestamp, MAX(rowid) 
-		FROM note_rev 
-		WHERE note_id = (?)`, id)
+		`SELECT note_id, blob_sha256, timestamp
+		FROM note_rev
+		WHERE note_id = (?)
+		ORDER BY rowid DESC
+		LIMIT 1`, id)
 
 	var nr NoteRev
-	var rowid int
-	if err := row.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp, &rowid); err != nil {
+	if err := row.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp); errors.Is(err, sql.ErrNoRows) {
+		return NoteRev{}, fmt.Errorf("note %d: %w", id, ErrNotFound)
+	} else if err != nil {
 		return NoteRev{}, fmt.Errorf("querying notes: %w", err)
 	}
 
+	nr.Timestamp = nr.Timestamp.Local()
 	return nr, nil
 }
 
-// GetNotes returns all current note revisions
+// GetNoteRevs returns every revision of a note, oldest first
+func (r Repo) GetNoteRevs(ctx context.Context, id int64) ([]NoteRev, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT note_id, blob_sha256, timestamp
+		FROM note_rev
+		WHERE note_id = (?)
+		ORDER BY rowid`, id)
+	if err != nil {
+		return nil, fmt.Errorf("querying note revs: %w", err)
+	}
+	defer rows.Close()
+
+	var revs []NoteRev
+
+	for rows.Next() {
+		var nr NoteRev
+		if err := rows.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp); err != nil {
+			return nil, fmt.Errorf("scanning note revs: %w", err)
+		}
+		nr.Timestamp = nr.Timestamp.Local()
+		revs = append(revs, nr)
+	}
+
+	return revs, nil
+}
+
+// FindNoteRev returns the latest revision of a note whose blob SHA256 starts
+// with the given prefix
+func (r Repo) FindNoteRev(ctx context.Context, id int64, sha256Prefix string) (NoteRev, error) {
+	revs, err := r.GetNoteRevs(ctx, id)
+	if err != nil {
+		return NoteRev{}, err
+	}
+
+	var found NoteRev
+	for _, nr := range revs {
+		if !strings.HasPrefix(nr.SHA256, strings.ToLower(sha256Prefix)) {
+			continue
+		}
+		if found != (NoteRev{}) && found.SHA256 != nr.SHA256 {
+			return NoteRev{}, fmt.Errorf("revision %q of note %d is ambiguous", sha256Prefix, id)
+		}
+		found = nr
+	}
+
+	if found == (NoteRev{}) {
+		return NoteRev{}, fmt.Errorf("revision %q of note %d: %w", sha256Prefix, id, ErrNotFound)
+	}
+
+	return found, nil
+}
+
+// SquashNoteRevs removes revisions of notes so that the current revisions
+// supersede them. Revisions are identified by their note, blob and
+// timestamp, so that revisions created by others in between are kept. The
+// current revision of a note is never removed. Blobs that are no longer
+// referenced by any revision are removed as well.
+func (r Repo) SquashNoteRevs(ctx context.Context, revs []NoteRev) error {
+	if len(revs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting squash note tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, nr := range revs {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM note_rev
+			WHERE note_id = (?)
+			AND blob_sha256 = (?)
+			AND timestamp = (?)
+			AND rowid != (SELECT MAX(rowid) FROM note_rev WHERE note_id = (?))`,
+			nr.ID, nr.SHA256, nr.Timestamp.UTC(), nr.ID)
+		if err != nil {
+			return fmt.Errorf("deleting squashed rev: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM blob
+			WHERE sha256 = (?)
+			AND NOT EXISTS (SELECT 1 FROM note_rev WHERE blob_sha256 = (?))`,
+			nr.SHA256, nr.SHA256)
+		if err != nil {
+			return fmt.Errorf("deleting squashed blob: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commiting squash note tx: %w", err)
+	}
+
+	return nil
+}
+
+// GetNotes returns all current note revisions, except of trashed notes
 func (r Repo) GetNotes(ctx context.Context) ([]NoteRev, error) {
 	rows, err := r.db.QueryContext(ctx,
 		`SELECT note_id, blob_sha256, timestamp, MAX(rowid) 
 		FROM note_rev 
+		WHERE note_id NOT IN (SELECT note_id FROM note_trash)
 		GROUP BY note_id
 		ORDER BY timestamp DESC`)
 	if err != nil {
@@ -337,56 +656,33 @@ func (r Repo) GetNotes(ctx context.Context) ([]NoteRev, error) {
 	return revs, nil
 }
 
-// FTSResult is the result of a full text search of the blob table
-type FTSResult struct {
-	noteRevRowID int64
-	SHA256       string
-	BM25         float32
-	Snippet      string
-}
-
-func (ftsr FTSResult) GetNoteRev(ctx context.Context, repo Repo) (NoteRev, error) {
-	row := repo.db.QueryRowContext(ctx,
-		`SELECT note_id, blob_sha256, timestamp 
-		FROM note_rev 
-		WHERE rowid = (?)`,
-		ftsr.noteRevRowID)
-	var nr NoteRev
-	if err := row.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp); err != nil {
-		return NoteRev{}, fmt.Errorf("scanning blob fts reults: %w", err)
-	}
-
-	nr.Timestamp = nr.Timestamp.Local()
-	return nr, nil
-}
-
-func (r Repo) FullTextSearch(ctx context.Context, searchTerm string) ([]FTSResult, error) {
-	rows, err := r.db.QueryContext(ctx,
-		`SELECT
-			note_rev_rowid,
-			blob_sha256,
-			bm25(note_fts, 0, 1.0),
-			snippet(note_fts, -1, "👉 ", " 👈", "...", 20)
-		FROM note_fts
-		WHERE blob_body MATCH (?)
-		ORDER BY bm25(note_fts, 0, 1.0);`,
-		searchTerm)
+// FindNoteByHeader returns the current revision of the most recently
+// modified note whose first line matches the header. Markdown heading
+// markers, surrounding whitespace and case are ignored.
+func (r Repo) FindNoteByHeader(ctx context.Context, header string) (NoteRev, error) {
+	notes, err := r.GetNotes(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("querying notes: %w", err)
+		return NoteRev{}, fmt.Errorf("listing notes: %w", err)
 	}
-	defer rows.Close()
 
-	var results []FTSResult
-
-	for rows.Next() {
-		var b FTSResult
-		if err := rows.Scan(&b.noteRevRowID, &b.SHA256, &b.BM25, &b.Snippet); err != nil {
-			return nil, fmt.Errorf("scanning blob fts reults: %w", err)
+	want := normalizeHeader(header)
+	for _, n := range notes {
+		head, err := n.GetBlobHead(ctx, r, len(header)+80)
+		if err != nil {
+			return NoteRev{}, fmt.Errorf("getting header of note %d: %w", n.ID, err)
+		}
+		if normalizeHeader(string(head)) == want {
+			return n, nil
 		}
-		results = append(results, b)
 	}
 
-	return results, nil
+	return NoteRev{}, fmt.Errorf("note with header %q: %w", header, ErrNotFound)
+}
+
+func normalizeHeader(header string) string {
+	header = strings.TrimSpace(header)
+	header = strings.TrimLeft(header, "#")
+	return strings.ToLower(strings.TrimSpace(header))
 }
 
 // WCTerm is a term in the word cloud
@@ -396,8 +692,16 @@ type WCTerm struct {
 	InstanceCount int64
 }
 
-// WordCloudTerms returns all search terms in the word cloud that are not stop words
+// WordCloudTerms returns all search terms in the word cloud that are not stop
+// words, most frequent first. Terms shorter than the wordcloud_min_length
+// config and, unless the wordcloud_numbers config is true, terms made only of
+// digits are left out.
 func (r Repo) WordCloudTerms(ctx context.Context) ([]WCTerm, error) {
+	filter, args, err := r.wordCloudFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := r.db.QueryContext(ctx,
 		`SELECT
 			term,
@@ -405,7 +709,8 @@ func (r Repo) WordCloudTerms(ctx context.Context) ([]WCTerm, error) {
 			cnt
 		FROM note_fts_vocab_cols
 		WHERE col = "blob_body"
-		ORDER BY cnt DESC`)
+		AND `+filter+`
+		ORDER BY cnt DESC`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying word cloud terms: %w", err)
 	}
@@ -424,8 +729,35 @@ func (r Repo) WordCloudTerms(ctx context.Context) ([]WCTerm, error) {
 	return results, nil
 }
 
-// Instances returns all the places the term occurs
+// wordCloudFilter returns the SQL condition on the term column excluding
+// stop words and the terms filtered by the word cloud configs
+func (r Repo) wordCloudFilter(ctx context.Context) (string, []interface{}, error) {
+	minLength, err := r.GetConfig(ctx, ConfigWCMinLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting word cloud min length config: %w", err)
+	}
+	numbers, err := r.GetConfig(ctx, ConfigWCNumbers)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting word cloud numbers config: %w", err)
+	}
+
+	filter := `term NOT IN (SELECT word FROM stop_words)
+		AND length(term) >= CAST((?) AS REAL)`
+	if numbers != "true" {
+		filter += `
+		AND term GLOB '*[^0-9]*'`
+	}
+
+	return filter, []interface{}{minLength}, nil
+}
+
+// Instances returns all the places the term occurs. The notes of a phrase
+// are found with a full text search of the phrase.
 func (wct WCTerm) Instances(ctx context.Context, repo Repo) ([]NoteRev, error) {
+	if wct.isPhrase() {
+		return wct.phraseInstances(ctx, repo)
+	}
+
 	rows, err := repo.db.QueryContext(ctx,
 		`SELECT DISTINCT note_rev.note_id, note_rev.blob_sha256, note_rev.timestamp
 		FROM note_fts_vocab_instances
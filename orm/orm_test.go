@@ -3,6 +3,9 @@ package orm_test
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -11,6 +14,30 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestLoadRepoURI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-nestable-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// nests can be file: URIs with parameters of their own
+	uri := "file:" + filepath.Join(dir, "uri.nest") + "?mode=rwc&cache=private"
+	repo, err := orm.InitRepo(uri)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	nr, err := repo.NewNote(ctx, bytes.NewBufferString("draft"))
+	require.NoError(t, err)
+
+	repo, err = orm.LoadRepo(uri)
+	require.NoError(t, err)
+	current, err := repo.GetCurrentNoteRev(ctx, nr.ID)
+	require.NoError(t, err)
+	require.Equal(t, nr.SHA256, current.SHA256)
+
+	_, err = os.Stat(filepath.Join(dir, "uri.nest"))
+	require.NoError(t, err)
+}
+
 func TestRepoConfig(t *testing.T) {
 	repo, cleanup := ormtest.TempTestRepo(t)
 	defer cleanup()
@@ -28,6 +55,17 @@ func TestRepoConfig(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "emacs", editorVal)
 
+	_, err = repo.GetConfig(ctx, "bogus")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	entries, err := repo.GetConfigEntries(ctx)
+	require.NoError(t, err)
+	require.Contains(t, entries, orm.ConfigEntry{
+		Key:         "editor",
+		Value:       "emacs",
+		Description: "external editor to edit notes",
+	})
+
 }
 
 func TestRepoNote(t *testing.T) {
@@ -84,6 +122,9 @@ func TestRepoNote(t *testing.T) {
 	require.Equal(t, note1.SHA256, curRev.SHA256)
 	ormtest.AssertNoteReader(t, ctx, repo, curRev, []byte(noteBody2))
 
+	_, err = repo.GetCurrentNoteRev(ctx, 404)
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
 	noteBody3 := "when notes are on a bagel you can have notes anytime"
 
 	expectNote2Rev1 := orm.NoteRev{
@@ -106,6 +147,121 @@ func TestRepoNote(t *testing.T) {
 	require.ElementsMatch(t, []orm.NoteRev{expectNote1Rev2, expectNote2Rev1}, notes)
 }
 
+func TestUpdateBlobIfCurrent(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"draft"})
+
+	// two writes from the same revision: only the first one is saved
+	first, err := revs[0].UpdateBlobIfCurrent(ctx, repo, bytes.NewBufferString("first edit"))
+	require.NoError(t, err)
+	_, err = revs[0].UpdateBlobIfCurrent(ctx, repo, bytes.NewBufferString("second edit"))
+	require.ErrorIs(t, err, orm.ErrConflict)
+
+	cur, err := repo.GetCurrentNoteRev(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, first, cur)
+	all, err := repo.GetNoteRevs(ctx, revs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	// writes from the current revision are saved
+	second, err := first.UpdateBlobIfCurrent(ctx, repo, bytes.NewBufferString("second edit"))
+	require.NoError(t, err)
+	ormtest.AssertNoteReader(t, ctx, repo, second, []byte("second edit"))
+
+	_, err = orm.NoteRev{Note: orm.Note{ID: 99}, Blob: orm.Blob{SHA256: first.SHA256}}.UpdateBlobIfCurrent(ctx, repo, bytes.NewBufferString("missing"))
+	require.ErrorIs(t, err, orm.ErrConflict)
+}
+
+func TestSquashNoteRevs(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"draft 1"})
+	base := revs[0]
+
+	for _, body := range []string{"draft 2", "draft 3", "final"} {
+		rev, err := revs[len(revs)-1].UpdateBlob(ctx, repo, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		revs = append(revs, rev)
+	}
+
+	// the current revision isn't squashed
+	require.NoError(t, repo.SquashNoteRevs(ctx, []orm.NoteRev{revs[1], revs[2], revs[3]}))
+
+	history, err := repo.GetNoteRevs(ctx, base.ID)
+	require.NoError(t, err)
+	require.Equal(t, []orm.NoteRev{revs[0], revs[3]}, history)
+
+	// squashed blobs are removed, but the current revision is searchable
+	_, err = revs[1].GetReader(ctx, repo)
+	require.Error(t, err)
+	results, err := repo.FullTextSearch(ctx, "final")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, revs[3].SHA256, results[0].SHA256)
+
+	// revisions created by others between squashed revisions are kept
+	autosave, err := revs[3].UpdateBlob(ctx, repo, bytes.NewBufferString("autosave"))
+	require.NoError(t, err)
+	other, err := autosave.UpdateBlob(ctx, repo, bytes.NewBufferString("concurrent edit"))
+	require.NoError(t, err)
+	final, err := other.UpdateBlob(ctx, repo, bytes.NewBufferString("final edit"))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SquashNoteRevs(ctx, []orm.NoteRev{autosave}))
+	history, err = repo.GetNoteRevs(ctx, base.ID)
+	require.NoError(t, err)
+	require.Equal(t, []orm.NoteRev{revs[0], revs[3], other, final}, history)
+	ormtest.AssertNoteReader(t, ctx, repo, other, []byte("concurrent edit"))
+}
+
+func TestAppendNote(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Inbox",
+		"# Inbox archive\n\n- old idea\n",
+	})
+
+	inbox, err := repo.FindNoteByHeader(ctx, "inbox")
+	require.NoError(t, err)
+	require.Equal(t, revs[0], inbox)
+
+	_, err = repo.FindNoteByHeader(ctx, "outbox")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	inbox, err = inbox.AppendBlob(ctx, repo, bytes.NewBufferString("- first idea"))
+	require.NoError(t, err)
+	ormtest.AssertNoteReader(t, ctx, repo, inbox, []byte("# Inbox\n- first idea\n"))
+
+	inbox, err = inbox.AppendBlob(ctx, repo, bytes.NewBufferString("- second idea\n"))
+	require.NoError(t, err)
+	ormtest.AssertNoteReader(t, ctx, repo, inbox, []byte("# Inbox\n- first idea\n- second idea\n"))
+
+	archive, err := revs[1].AppendBlob(ctx, repo, bytes.NewBufferString("- new idea"))
+	require.NoError(t, err)
+	ormtest.AssertNoteReader(t, ctx, repo, archive, []byte("# Inbox archive\n\n- old idea\n- new idea\n"))
+}
+
 func TestBlobFTS(t *testing.T) {
 	clockCleanup := ormtest.MockClock()
 	defer clockCleanup()
@@ -138,6 +294,27 @@ func TestBlobFTS(t *testing.T) {
 	require.Len(t, results, 1)
 	require.Equal(t, revs[1].SHA256, results[0].SHA256)
 
+	// Results report the line of the first match
+	revs[2], err = revs[2].UpdateBlob(ctx, repo, bytes.NewBufferString("mind map\n\nnestable is not a note"))
+	require.NoError(t, err)
+	results, err = repo.FullTextSearch(ctx, "nestable")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		switch r.SHA256 {
+		case revs[0].SHA256:
+			require.Equal(t, 1, r.Line)
+		case revs[2].SHA256:
+			require.Equal(t, 3, r.Line)
+		default:
+			t.Fatalf("unexpected result %v", r)
+		}
+	}
+
+	results, err = repo.FullTextSearch(ctx, "once")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
 	// Fetch a note revision from a result
 	nr, err := results[0].GetNoteRev(ctx, repo)
 	require.NoError(t, err)
@@ -154,26 +331,47 @@ func TestWordCloud(t *testing.T) {
 	ctx := context.Background()
 
 	notes := []string{
-		"a aa aaa",
-		"a b c",
-		"a b bc",
+		"the cluster and the nodes",
+		"the cluster is ok at 42",
+		"nodes of a cluster",
 	}
 	revs := ormtest.InsertTestNotes(t, ctx, repo, notes)
 
-	// Fetch word cloud
+	// Fetch word cloud, without stop words, short terms and numbers
 	terms, err := repo.WordCloudTerms(ctx)
 	require.NoError(t, err)
 	expectTerms := []orm.WCTerm{
-		orm.WCTerm{Term: "aa", NoteCount: 1, InstanceCount: 1},
-		orm.WCTerm{Term: "aaa", NoteCount: 1, InstanceCount: 1},
-		orm.WCTerm{Term: "bc", NoteCount: 1, InstanceCount: 1},
-		orm.WCTerm{Term: "c", NoteCount: 1, InstanceCount: 1},
-		orm.WCTerm{Term: "b", NoteCount: 2, InstanceCount: 2},
-		orm.WCTerm{Term: "a", NoteCount: 3, InstanceCount: 3},
+		orm.WCTerm{Term: "cluster", NoteCount: 3, InstanceCount: 3},
+		orm.WCTerm{Term: "nodes", NoteCount: 2, InstanceCount: 2},
 	}
-	require.ElementsMatch(t, expectTerms, terms)
+	require.Equal(t, expectTerms, terms)
+
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigWCMinLength, "2"))
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigWCNumbers, "true"))
+	terms, err = repo.WordCloudTerms(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, append([]orm.WCTerm{
+		orm.WCTerm{Term: "ok", NoteCount: 1, InstanceCount: 1},
+		orm.WCTerm{Term: "42", NoteCount: 1, InstanceCount: 1},
+	}, expectTerms...), terms)
+
+	require.NoError(t, repo.AddStopWords(ctx, "OK", "42", "ok"))
+	terms, err = repo.WordCloudTerms(ctx)
+	require.NoError(t, err)
+	require.Equal(t, expectTerms, terms)
+
+	stopWords, err := repo.GetStopWords(ctx)
+	require.NoError(t, err)
+	require.Contains(t, stopWords, "the")
+	require.Contains(t, stopWords, "ok")
+
+	require.ErrorIs(t, repo.DeleteStopWords(ctx, "42", "nope"), orm.ErrNotFound)
+	require.NoError(t, repo.DeleteStopWords(ctx, "42", "ok"))
+	terms, err = repo.WordCloudTerms(ctx)
+	require.NoError(t, err)
+	require.Len(t, terms, 4)
 
-	instanceRevs, err := expectTerms[5].Instances(ctx, repo)
+	instanceRevs, err := expectTerms[0].Instances(ctx, repo)
 	require.NoError(t, err)
 	t.Log(instanceRevs)
 	require.Equal(t, revs, instanceRevs)
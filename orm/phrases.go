@@ -0,0 +1,193 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxPhraseTerms is the number of terms of the longest phrases
+	maxPhraseTerms = 3
+	// minPhraseCount is how many times a phrase must appear in the notes
+	// to be part of the word cloud
+	minPhraseCount = 2
+)
+
+// isPhrase reports whether the word cloud term is a phrase of several terms
+func (wct WCTerm) isPhrase() bool {
+	return strings.Contains(wct.Term, " ")
+}
+
+// WordCloudPhrases returns the phrases of two or three consecutive terms
+// that appear at least twice in the notes modified within the window, most
+// frequent first. Phrases starting or ending with a stop word, such as "of
+// the", are left out, and so are phrases of numbers unless the
+// wordcloud_numbers config is true.
+func (r Repo) WordCloudPhrases(ctx context.Context, tw TimeWindow) ([]WCTerm, error) {
+	stopWords, err := r.GetStopWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isStopWord := make(map[string]bool, len(stopWords))
+	for _, w := range stopWords {
+		isStopWord[w] = true
+	}
+
+	numbers, err := r.GetConfig(ctx, ConfigWCNumbers)
+	if err != nil {
+		return nil, fmt.Errorf("getting word cloud numbers config: %w", err)
+	}
+
+	var (
+		window string
+		args   []interface{}
+	)
+	const layout = "2006-01-02 15:04:05"
+	if !tw.Since.IsZero() {
+		window += `
+		AND julianday(nr.timestamp) >= julianday(?)`
+		args = append(args, tw.Since.UTC().Format(layout))
+	}
+	if !tw.Until.IsZero() {
+		window += `
+		AND julianday(nr.timestamp) < julianday(?)`
+		args = append(args, tw.Until.UTC().Format(layout))
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT vi.doc, vi."offset", vi.term
+		FROM note_fts_vocab_instances AS vi
+		INNER JOIN note_fts
+			ON vi.doc = note_fts.rowid
+		INNER JOIN note_rev AS nr
+			ON note_fts.note_rev_rowid = nr.rowid
+		WHERE vi.col = 'blob_body'`+window+`
+		ORDER BY vi.doc, vi."offset"`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying word cloud phrases: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		counts  = map[string]*WCTerm{}
+		lastDoc = map[string]int64{}
+		// the terms preceding the current term in the same note
+		prev       []string
+		prevDoc    int64
+		prevOffset int64 = -2
+	)
+	for rows.Next() {
+		var (
+			doc, offset int64
+			term        string
+		)
+		if err := rows.Scan(&doc, &offset, &term); err != nil {
+			return nil, fmt.Errorf("scanning word cloud phrases: %w", err)
+		}
+
+		if doc != prevDoc || offset != prevOffset+1 {
+			prev = prev[:0]
+		}
+		prevDoc, prevOffset = doc, offset
+
+		prev = append(prev, term)
+		if len(prev) > maxPhraseTerms {
+			prev = prev[1:]
+		}
+
+		for n := 2; n <= len(prev); n++ {
+			terms := prev[len(prev)-n:]
+			if isStopWord[terms[0]] || isStopWord[terms[len(terms)-1]] {
+				continue
+			}
+			if numbers != "true" && allNumbers(terms) {
+				continue
+			}
+
+			phrase := strings.Join(terms, " ")
+			c, ok := counts[phrase]
+			if !ok {
+				c = &WCTerm{Term: phrase}
+				counts[phrase] = c
+			}
+			c.InstanceCount++
+			if last, seen := lastDoc[phrase]; !seen || last != doc {
+				c.NoteCount++
+				lastDoc[phrase] = doc
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying word cloud phrases: %w", err)
+	}
+
+	var phrases []WCTerm
+	for _, c := range counts {
+		if c.InstanceCount >= minPhraseCount {
+			phrases = append(phrases, *c)
+		}
+	}
+	sortWCTerms(phrases)
+
+	return phrases, nil
+}
+
+// sortWCTerms sorts word cloud terms by frequency, most frequent first
+func sortWCTerms(terms []WCTerm) {
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].InstanceCount != terms[j].InstanceCount {
+			return terms[i].InstanceCount > terms[j].InstanceCount
+		}
+		if terms[i].NoteCount != terms[j].NoteCount {
+			return terms[i].NoteCount > terms[j].NoteCount
+		}
+		return terms[i].Term < terms[j].Term
+	})
+}
+
+func allNumbers(terms []string) bool {
+	for _, t := range terms {
+		if strings.Trim(t, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// phraseInstances returns the current revisions of the notes matching the
+// phrase, best match first
+func (wct WCTerm) phraseInstances(ctx context.Context, repo Repo) ([]NoteRev, error) {
+	phrase := `"` + strings.ReplaceAll(wct.Term, `"`, `""`) + `"`
+
+	rows, err := repo.db.QueryContext(ctx,
+		`SELECT nr.note_id, nr.blob_sha256, nr.timestamp
+		FROM note_fts
+		INNER JOIN note_rev AS nr
+			ON note_fts.note_rev_rowid = nr.rowid
+		WHERE note_fts.blob_body MATCH (?)
+		ORDER BY bm25(note_fts, 0, 1.0), nr.note_id`,
+		phrase)
+	if err != nil {
+		return nil, fmt.Errorf("querying phrase instances for %q: %w", wct.Term, err)
+	}
+	defer rows.Close()
+
+	var results []NoteRev
+	for rows.Next() {
+		var nr NoteRev
+		if err := rows.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp); err != nil {
+			return nil, fmt.Errorf("scanning phrase instances: %w", err)
+		}
+		nr.Timestamp = nr.Timestamp.Local()
+		results = append(results, nr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying phrase instances for %q: %w", wct.Term, err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,48 @@
+package orm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordCloudPhrases(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"the load balancer failed over",
+		"restart the load balancer, then postgres failover",
+		"postgres failover of the load balancer",
+		"port 8080 8080 and 8080 8080",
+		"load\n\nbalancer",
+	})
+
+	phrases, err := repo.WordCloudPhrases(ctx, orm.TimeWindow{})
+	require.NoError(t, err)
+	require.Equal(t, []orm.WCTerm{
+		// consecutive terms form a phrase even across lines
+		{Term: "load balancer", NoteCount: 4, InstanceCount: 4},
+		{Term: "postgres failover", NoteCount: 2, InstanceCount: 2},
+	}, phrases)
+
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigWCNumbers, "true"))
+	phrases, err = repo.WordCloudPhrases(ctx, orm.TimeWindow{Since: time.Unix(4, 0)})
+	require.NoError(t, err)
+	require.Equal(t, []orm.WCTerm{
+		{Term: "8080 8080", NoteCount: 1, InstanceCount: 2},
+	}, phrases)
+
+	instances, err := orm.WCTerm{Term: "postgres failover"}.Instances(ctx, repo)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []orm.NoteRev{revs[1], revs[2]}, instances)
+}
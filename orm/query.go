@@ -0,0 +1,460 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryError is a syntax error in a note query at a position of the query
+type QueryError struct {
+	Query string
+	// Pos is the byte offset of the error in the query
+	Pos int
+	Msg string
+}
+
+func (qe QueryError) Error() string {
+	return fmt.Sprintf("invalid query at column %d: %s", qe.Pos+1, qe.Msg)
+}
+
+func (qe QueryError) Unwrap() error { return ErrBadQuery }
+
+// Pointer returns the query with a caret under the error position
+func (qe QueryError) Pointer() string {
+	return qe.Query + "\n" + strings.Repeat(" ", len([]rune(qe.Query[:qe.Pos]))) + "^"
+}
+
+// Query is a parsed note query, e.g.
+//
+//	text:"k8s" modified:>2026-01-01 revs:>=3 sort:-modified
+//
+// Terms without a field match the text of the notes. Every term must match.
+type Query struct {
+	// Text is the FTS5 expression of the text terms, empty when there are none
+	Text string
+
+	filters []queryFilter
+	sorts   []string
+}
+
+type queryFilter struct {
+	expr string
+	args []interface{}
+}
+
+type queryFieldKind int
+
+const (
+	queryInt queryFieldKind = iota
+	queryDate
+)
+
+type queryField struct {
+	column string
+	kind   queryFieldKind
+}
+
+// queryFields are the fields that filter and sort notes. The columns refer to
+// the query built by Repo.QueryNotes.
+var queryFields = map[string]queryField{
+	"id":       {column: "nr.note_id", kind: queryInt},
+	"created":  {column: "cur.created", kind: queryDate},
+	"modified": {column: "julianday(nr.timestamp)", kind: queryDate},
+	"revs":     {column: "cur.revs", kind: queryInt},
+	"length":   {column: "length(blob.body)", kind: queryInt},
+}
+
+// queryTerm is a term of a query with its position
+type queryTerm struct {
+	pos      int
+	field    string
+	value    string
+	valuePos int
+	quoted   bool
+}
+
+// ParseQuery parses a note query. Fields are text, id, created, modified,
+// revs, length and sort. Numbers and dates can be compared with <, <=, >,
+// >= or a range such as 2026-01-01..2026-01-31.
+func ParseQuery(query string) (Query, error) {
+	terms, err := lexQuery(query)
+	if err != nil {
+		return Query{}, err
+	}
+
+	var (
+		q       Query
+		text    []string
+		rankPos = -1
+	)
+	for _, t := range terms {
+		switch t.field {
+		case "", "text":
+			phrase, err := textPhrase(query, t)
+			if err != nil {
+				return Query{}, err
+			}
+			text = append(text, phrase)
+
+		case "sort":
+			sort, err := parseSort(query, t)
+			if err != nil {
+				return Query{}, err
+			}
+			if strings.HasPrefix(sort, "rank ") {
+				rankPos = t.valuePos
+			}
+			q.sorts = append(q.sorts, sort)
+
+		default:
+			f, ok := queryFields[t.field]
+			if !ok {
+				return Query{}, QueryError{Query: query, Pos: t.pos, Msg: fmt.Sprintf("unknown field %q", t.field)}
+			}
+
+			filter, err := parseFilter(query, t, f)
+			if err != nil {
+				return Query{}, err
+			}
+			q.filters = append(q.filters, filter)
+		}
+	}
+
+	q.Text = strings.Join(text, " ")
+
+	if rankPos >= 0 && q.Text == "" {
+		return Query{}, QueryError{Query: query, Pos: rankPos, Msg: "sorting by rank requires text terms"}
+	}
+
+	return q, nil
+}
+
+// lexQuery splits a query into terms separated by whitespace. Double quotes
+// group a value containing whitespace.
+func lexQuery(query string) ([]queryTerm, error) {
+	var terms []queryTerm
+
+	i := 0
+	for i < len(query) {
+		if query[i] == ' ' || query[i] == '\t' || query[i] == '\n' {
+			i++
+			continue
+		}
+
+		t := queryTerm{pos: i, valuePos: i}
+
+		// a field name is a word followed by a colon
+		j := i
+		for j < len(query) && (query[j] == '_' || unicode.IsLetter(rune(query[j]))) {
+			j++
+		}
+		if j > i && j < len(query) && query[j] == ':' {
+			t.field = strings.ToLower(query[i:j])
+			t.valuePos = j + 1
+			i = j + 1
+		}
+
+		var value strings.Builder
+		for i < len(query) && !strings.ContainsRune(" \t\n", rune(query[i])) {
+			if query[i] != '"' {
+				value.WriteByte(query[i])
+				i++
+				continue
+			}
+
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, QueryError{Query: query, Pos: i, Msg: "unterminated double quote"}
+			}
+			value.WriteString(query[i+1 : i+1+end])
+			t.quoted = true
+			i += end + 2
+		}
+		t.value = value.String()
+
+		if t.value == "" && !t.quoted {
+			return nil, QueryError{Query: query, Pos: t.valuePos, Msg: fmt.Sprintf("missing value for field %q", t.field)}
+		}
+
+		terms = append(terms, t)
+	}
+
+	return terms, nil
+}
+
+// textPhrase converts a text term to an FTS5 phrase. Everything is quoted so
+// that punctuation is searched for rather than parsed, except for a trailing
+// asterisk which matches a prefix.
+func textPhrase(query string, t queryTerm) (string, error) {
+	value, prefix := t.value, false
+	if !t.quoted && strings.HasSuffix(value, "*") {
+		value, prefix = strings.TrimSuffix(value, "*"), true
+	}
+	if strings.TrimSpace(value) == "" {
+		return "", QueryError{Query: query, Pos: t.valuePos, Msg: "empty text"}
+	}
+
+	phrase := `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	if prefix {
+		phrase += "*"
+	}
+	return phrase, nil
+}
+
+func parseSort(query string, t queryTerm) (string, error) {
+	name, dir := t.value, "ASC"
+	switch {
+	case strings.HasPrefix(name, "-"):
+		name, dir = name[1:], "DESC"
+	case strings.HasPrefix(name, "+"):
+		name = name[1:]
+	}
+
+	if name == "rank" {
+		// the best BM25 scores are the lowest
+		return "rank " + dir, nil
+	}
+
+	f, ok := queryFields[strings.ToLower(name)]
+	if !ok {
+		return "", QueryError{Query: query, Pos: t.valuePos, Msg: fmt.Sprintf("unknown sort field %q", name)}
+	}
+	return f.column + " " + dir, nil
+}
+
+// parseFilter parses a comparison such as >=3, <2026-01-01, 5 or a range
+// such as 1..10. Ranges include both ends.
+func parseFilter(query string, t queryTerm, f queryField) (queryFilter, error) {
+	op, value := "=", t.value
+	for _, o := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, o) {
+			op, value = o, value[len(o):]
+			break
+		}
+	}
+	pos := t.valuePos + len(t.value) - len(value)
+
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		if op != "=" {
+			return queryFilter{}, QueryError{Query: query, Pos: t.valuePos, Msg: "a range can't be compared"}
+		}
+
+		low, _, err := parseBound(query, pos, lo, f.kind)
+		if err != nil {
+			return queryFilter{}, err
+		}
+		high, next, err := parseBound(query, pos+len(lo)+2, hi, f.kind)
+		if err != nil {
+			return queryFilter{}, err
+		}
+		if f.kind == queryDate {
+			return queryFilter{expr: f.column + " >= julianday(?) AND " + f.column + " < julianday(?)", args: []interface{}{low, next}}, nil
+		}
+		return queryFilter{expr: f.column + " BETWEEN (?) AND (?)", args: []interface{}{low, high}}, nil
+	}
+
+	start, next, err := parseBound(query, pos, value, f.kind)
+	if err != nil {
+		return queryFilter{}, err
+	}
+	if f.kind == queryInt {
+		return queryFilter{expr: f.column + " " + op + " (?)", args: []interface{}{start}}, nil
+	}
+
+	// a date covers a whole day, from its start until the start of the
+	// next day
+	switch op {
+	case "=":
+		return queryFilter{expr: f.column + " >= julianday(?) AND " + f.column + " < julianday(?)", args: []interface{}{start, next}}, nil
+	case ">":
+		return queryFilter{expr: f.column + " >= julianday(?)", args: []interface{}{next}}, nil
+	case "<=":
+		return queryFilter{expr: f.column + " < julianday(?)", args: []interface{}{next}}, nil
+	default:
+		return queryFilter{expr: f.column + " " + op + " julianday(?)", args: []interface{}{start}}, nil
+	}
+}
+
+// parseBound parses a number, or a date in the local time zone. For dates,
+// it returns the start of the day and of the next day in UTC.
+func parseBound(query string, pos int, value string, kind queryFieldKind) (interface{}, interface{}, error) {
+	if kind == queryInt {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, nil, QueryError{Query: query, Pos: pos, Msg: fmt.Sprintf("invalid number %q", value)}
+		}
+		return n, n, nil
+	}
+
+	day, err := time.ParseInLocation(DayLayout, value, time.Local)
+	if err != nil {
+		return nil, nil, QueryError{Query: query, Pos: pos, Msg: fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", value)}
+	}
+
+	const layout = "2006-01-02 15:04:05"
+	return day.UTC().Format(layout), day.AddDate(0, 0, 1).UTC().Format(layout), nil
+}
+
+// QueryResult is a note matching a query
+type QueryResult struct {
+	NoteRev
+	// Revs is the number of revisions of the note
+	Revs int
+	// Length is the size of the current revision in bytes
+	Length int
+	// BM25, Score, Snippet and Line describe the text match, when the query
+	// has text terms. Score is the BM25 score blended with the ranking when
+	// the query doesn't sort the notes.
+	BM25    float32
+	Score   float32
+	Snippet string
+	Line    int
+}
+
+// QueryNotes returns the current revisions of the notes matching the query.
+// Notes are sorted by rank when the query has text terms and by modification
+// time otherwise, unless the query sorts them. Text matches are ranked like
+// FullTextSearchOpts ranks them. Only the limit, offset, highlight and
+// ranking search options apply.
+func (r Repo) QueryNotes(ctx context.Context, q Query, opts SearchOptions) ([]QueryResult, error) {
+	if opts.History {
+		return nil, errors.New("queries can't search the history of notes")
+	}
+	if err := opts.checkPaging(); err != nil {
+		return nil, err
+	}
+	if opts.HighlightStart == "" && opts.HighlightEnd == "" {
+		opts.HighlightStart, opts.HighlightEnd = "👉 ", " 👈"
+	}
+
+	var (
+		columns = `nr.note_id, nr.blob_sha256, nr.timestamp, cur.revs, length(blob.body),
+			0, "", ""`
+		join  string
+		where = []string{"1"}
+		args  []interface{}
+		order = q.sorts
+		// ranking orders text matches when the query doesn't sort them
+		ranking *Ranking
+	)
+
+	if q.Text != "" && len(order) == 0 {
+		ranking = opts.Ranking
+		if ranking == nil {
+			rk, err := r.GetRanking(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ranking = &rk
+		}
+	}
+
+	if q.Text != "" {
+		columns = `nr.note_id, nr.blob_sha256, nr.timestamp, cur.revs, length(blob.body),
+			bm25(note_fts, 0, 1.0) AS rank,
+			snippet(note_fts, -1, ?, ?, "...", 20),
+			highlight(note_fts, 2, char(2), char(3))`
+		args = append(args, opts.HighlightStart, opts.HighlightEnd)
+		join = "INNER JOIN note_fts ON note_fts.note_rev_rowid = cur.rev_rowid"
+		where = append(where, "note_fts.blob_body MATCH (?)")
+		args = append(args, q.Text)
+		if len(order) == 0 {
+			order = []string{"rank ASC"}
+		}
+	}
+	if len(order) == 0 {
+		order = []string{"julianday(nr.timestamp) DESC"}
+	}
+
+	for _, f := range q.filters {
+		where = append(where, "("+f.expr+")")
+		args = append(args, f.args...)
+	}
+
+	// the ranking depends on every match, so ranked matches are paged
+	// afterwards
+	limit, offset := -1, 0
+	if ranking == nil {
+		if opts.Limit > 0 {
+			limit = opts.Limit
+		}
+		offset = opts.Offset
+	}
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(
+		`WITH cur AS (
+			SELECT
+				note_id,
+				MAX(rowid) AS rev_rowid,
+				MIN(julianday(timestamp)) AS created,
+				COUNT(*) AS revs
+			FROM note_rev
+			WHERE note_id NOT IN (SELECT note_id FROM note_trash)
+			GROUP BY note_id
+		)
+		SELECT %s
+		FROM cur
+		INNER JOIN note_rev AS nr ON nr.rowid = cur.rev_rowid
+		INNER JOIN blob ON blob.sha256 = nr.blob_sha256
+		%s
+		WHERE %s
+		ORDER BY %s, nr.note_id DESC
+		LIMIT (?) OFFSET (?);`,
+		columns, join, strings.Join(where, " AND "), strings.Join(order, ", ")),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying notes: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		results []QueryResult
+		now     = clock()
+	)
+	for rows.Next() {
+		var (
+			qr        QueryResult
+			highlight string
+		)
+		if err := rows.Scan(&qr.ID, &qr.SHA256, &qr.Timestamp, &qr.Revs, &qr.Length,
+			&qr.BM25, &qr.Snippet, &highlight); err != nil {
+			return nil, fmt.Errorf("scanning query results: %w", err)
+		}
+		qr.Timestamp = qr.Timestamp.Local()
+		if q.Text != "" {
+			qr.Line = matchLine(highlight)
+			qr.Score = qr.BM25
+		}
+		if ranking != nil {
+			qr.Score = ranking.score(qr.BM25, qr.Line, now.Sub(qr.Timestamp))
+		}
+		results = append(results, qr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying notes: %w", err)
+	}
+
+	if ranking == nil {
+		return results, nil
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score < results[j].Score })
+	if opts.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(results) {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,101 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := orm.ParseQuery(`text:"k8s prod" 10.0.3.1 nest* modified:>2026-01-01 sort:-modified`)
+	require.NoError(t, err)
+	require.Equal(t, `"k8s prod" "10.0.3.1" "nest"*`, q.Text)
+
+	for _, tc := range []struct {
+		query string
+		pos   int
+	}{
+		{query: `bogus:1`, pos: 0},
+		{query: `id:>x`, pos: 4},
+		{query: `revs:1..y`, pos: 8},
+		{query: `modified:2026-13-01`, pos: 9},
+		{query: `created:>=2026-01-01..2026-02-01`, pos: 8},
+		{query: `note "unterminated`, pos: 5},
+		{query: `id:`, pos: 3},
+		{query: `text:""`, pos: 5},
+		{query: `sort:name`, pos: 5},
+		{query: `sort:rank`, pos: 5},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := orm.ParseQuery(tc.query)
+			require.ErrorIs(t, err, orm.ErrBadQuery)
+
+			var qe orm.QueryError
+			require.True(t, errors.As(err, &qe))
+			require.Equal(t, tc.pos, qe.Pos, qe.Error())
+		})
+	}
+}
+
+func TestQueryNotes(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"k8s cluster at 10.0.3.1",
+		"grocery list",
+		"k8s k8s k8s upgrade",
+	})
+	revs[1], _ = revs[1].UpdateBlob(ctx, repo, bytes.NewBufferString("grocery list: milk"))
+
+	query := func(q string) []int64 {
+		t.Helper()
+		parsed, err := orm.ParseQuery(q)
+		require.NoError(t, err)
+		results, err := repo.QueryNotes(ctx, parsed, orm.SearchOptions{})
+		require.NoError(t, err)
+
+		ids := []int64{}
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	// without text terms, the most recently modified notes come first
+	require.Equal(t, []int64{2, 3, 1}, query(""))
+	require.Equal(t, []int64{3, 1}, query("k8s"))
+	require.Equal(t, []int64{1, 3}, query("k8s sort:id"))
+	require.Equal(t, []int64{1}, query(`"10.0.3.1"`))
+	require.Equal(t, []int64{2}, query("revs:>1"))
+	require.Equal(t, []int64{2, 1}, query("id:1..2 sort:-id"))
+	require.Equal(t, []int64{3}, query("id:>=2 length:>18"))
+
+	// the mock clock starts at the epoch
+	epoch := time.Unix(0, 0).Local().Format(orm.DayLayout)
+	require.Equal(t, []int64{2, 3, 1}, query("created:"+epoch))
+	require.Equal(t, []int64{}, query("modified:>"+epoch))
+	require.Equal(t, []int64{2, 3, 1}, query("modified:<="+epoch))
+
+	parsed, err := orm.ParseQuery("k8s")
+	require.NoError(t, err)
+	results, err := repo.QueryNotes(ctx, parsed, orm.SearchOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, revs[0], results[0].NoteRev)
+	require.Equal(t, 1, results[0].Revs)
+	require.Equal(t, len("k8s cluster at 10.0.3.1"), results[0].Length)
+	require.Equal(t, 1, results[0].Line)
+	require.Contains(t, results[0].Snippet, "👉 k8s 👈")
+}
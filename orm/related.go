@@ -0,0 +1,161 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RelatedNote is a note similar to another note
+type RelatedNote struct {
+	NoteRev
+	// Similarity is the cosine similarity of the TF-IDF weights of the terms
+	// of both notes, from 0 for no terms in common to 1 for the same terms
+	Similarity float64
+}
+
+// termVector maps the terms of a note to their weight
+type termVector map[string]float64
+
+func (tv termVector) norm() float64 {
+	var sum float64
+	for _, w := range tv {
+		sum += w * w
+	}
+	return math.Sqrt(sum)
+}
+
+// cosine is the cosine similarity of two term vectors
+func (tv termVector) cosine(other termVector) float64 {
+	var dot float64
+	for term, w := range tv {
+		dot += w * other[term]
+	}
+	if dot == 0 {
+		return 0
+	}
+	return dot / (tv.norm() * other.norm())
+}
+
+// noteTermCounts counts the terms of the current revision of each note,
+// excluding stop words
+func (r Repo) noteTermCounts(ctx context.Context) (map[int64]map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT note_rev.note_id, term, COUNT(*)
+		FROM note_fts_vocab_instances
+		INNER JOIN note_fts
+			ON note_fts_vocab_instances.doc = note_fts.rowid
+		INNER JOIN note_rev
+			ON note_fts.note_rev_rowid = note_rev.rowid
+		WHERE col = 'blob_body'
+		AND term NOT IN (SELECT word FROM stop_words)
+		GROUP BY note_rev.note_id, term`)
+	if err != nil {
+		return nil, fmt.Errorf("querying note terms: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[int64]map[string]int{}
+	for rows.Next() {
+		var (
+			id    int64
+			term  string
+			count int
+		)
+		if err := rows.Scan(&id, &term, &count); err != nil {
+			return nil, fmt.Errorf("scanning note terms: %w", err)
+		}
+		if counts[id] == nil {
+			counts[id] = map[string]int{}
+		}
+		counts[id][term] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying note terms: %w", err)
+	}
+
+	return counts, nil
+}
+
+// tfidfVectors weighs the term counts of each note by how rare the terms are
+// across all notes
+func tfidfVectors(counts map[int64]map[string]int) map[int64]termVector {
+	df := map[string]int{}
+	for _, terms := range counts {
+		for term := range terms {
+			df[term]++
+		}
+	}
+
+	n := float64(len(counts))
+	vectors := make(map[int64]termVector, len(counts))
+	for id, terms := range counts {
+		tv := make(termVector, len(terms))
+		for term, tf := range terms {
+			// smoothed IDF keeps terms found in every note slightly relevant
+			idf := math.Log((1+n)/(1+float64(df[term]))) + 1
+			tv[term] = (1 + math.Log(float64(tf))) * idf
+		}
+		vectors[id] = tv
+	}
+
+	return vectors
+}
+
+// RelatedNotes returns up to n notes most similar to the note with the ID,
+// the most similar first. Similarity is computed locally from the TF-IDF
+// weights of the terms in the current revisions of the notes.
+func (r Repo) RelatedNotes(ctx context.Context, id int64, n int) ([]RelatedNote, error) {
+	if _, err := r.GetCurrentNoteRev(ctx, id); err != nil {
+		return nil, err
+	}
+
+	counts, err := r.noteTermCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := tfidfVectors(counts)
+	target, ok := vectors[id]
+	if !ok {
+		return nil, nil
+	}
+
+	type similarity struct {
+		id    int64
+		score float64
+	}
+	var similar []similarity
+	for other, tv := range vectors {
+		if other == id {
+			continue
+		}
+		if score := target.cosine(tv); score > 0 {
+			similar = append(similar, similarity{id: other, score: score})
+		}
+	}
+
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].score != similar[j].score {
+			return similar[i].score > similar[j].score
+		}
+		return similar[i].id < similar[j].id
+	})
+	if n > 0 && len(similar) > n {
+		similar = similar[:n]
+	}
+
+	related := make([]RelatedNote, len(similar))
+	for i, s := range similar {
+		nr, err := r.GetCurrentNoteRev(ctx, s.id)
+		if err != nil {
+			return nil, err
+		}
+		related[i] = RelatedNote{NoteRev: nr, Similarity: s.score}
+	}
+
+	return related, nil
+}
@@ -0,0 +1,63 @@
+package orm_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelatedNotes(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"kubernetes cluster upgrade failed on the prod nodes",
+		"grocery list: milk, eggs and bread",
+		"prod kubernetes nodes ran out of disk",
+		"bread recipe with eggs",
+		"",
+	})
+
+	// terms of 2 of the 4 notes with terms, and of a single note, weigh
+	shared := math.Log(5.0/3) + 1
+	unique := math.Log(5.0/2) + 1
+	cosine := func(common, onlyA, onlyB float64) float64 {
+		dot := common * shared * shared
+		normA := math.Sqrt(common*shared*shared + onlyA*unique*unique)
+		normB := math.Sqrt(common*shared*shared + onlyB*unique*unique)
+		return dot / (normA * normB)
+	}
+
+	// kubernetes, nodes and prod are common, 3 and 2 terms are not
+	related, err := repo.RelatedNotes(ctx, revs[0].ID, 5)
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	require.Equal(t, revs[2], related[0].NoteRev)
+	require.InDelta(t, cosine(3, 3, 2), related[0].Similarity, 1e-9)
+	require.InDelta(t, 0.4301, related[0].Similarity, 1e-4)
+
+	// bread and eggs are common, 3 and 1 terms are not
+	related, err = repo.RelatedNotes(ctx, revs[1].ID, 1)
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	require.Equal(t, revs[3], related[0].NoteRev)
+	require.InDelta(t, cosine(2, 3, 1), related[0].Similarity, 1e-9)
+	require.InDelta(t, 0.4030, related[0].Similarity, 1e-4)
+
+	// a note without terms isn't related to anything
+	related, err = repo.RelatedNotes(ctx, revs[4].ID, 5)
+	require.NoError(t, err)
+	require.Empty(t, related)
+
+	_, err = repo.RelatedNotes(ctx, 404, 5)
+	require.ErrorIs(t, err, orm.ErrNotFound)
+}
@@ -0,0 +1,153 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SavedSearch is a named full text search query stored in the nest. A term
+// of "@name" refers to it wherever a search term is accepted.
+type SavedSearch struct {
+	Name  string
+	Query string
+	// LastRun is when the search was last run, zero if it never ran
+	LastRun time.Time
+}
+
+// SaveSearch creates the saved search, or replaces the query of an existing
+// saved search with the same name. The query must be valid.
+func (r Repo) SaveSearch(ctx context.Context, ss SavedSearch) error {
+	var one int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT 1 FROM note_fts WHERE blob_body MATCH (?) LIMIT 1",
+		ss.Query).Scan(&one)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("saving search %q: %w", ss.Name, checkQueryError(ss.Query, err))
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO saved_search (name, query) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET query = excluded.query, last_run = NULL`,
+		ss.Name, ss.Query)
+	if err != nil {
+		return fmt.Errorf("saving search %q: %w", ss.Name, err)
+	}
+	return nil
+}
+
+// GetSavedSearch returns the saved search with the name
+func (r Repo) GetSavedSearch(ctx context.Context, name string) (SavedSearch, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT name, query, last_run FROM saved_search WHERE name = (?)", name)
+
+	ss, err := scanSavedSearch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SavedSearch{}, fmt.Errorf("saved search %q: %w", name, ErrNotFound)
+	} else if err != nil {
+		return SavedSearch{}, fmt.Errorf("getting saved search %q: %w", name, err)
+	}
+
+	return ss, nil
+}
+
+// GetSavedSearches returns all saved searches ordered by name
+func (r Repo) GetSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT name, query, last_run FROM saved_search ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("querying saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		ss, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning saved searches: %w", err)
+		}
+		searches = append(searches, ss)
+	}
+
+	return searches, nil
+}
+
+func scanSavedSearch(row interface{ Scan(...interface{}) error }) (SavedSearch, error) {
+	var (
+		ss      SavedSearch
+		lastRun sql.NullTime
+	)
+	if err := row.Scan(&ss.Name, &ss.Query, &lastRun); err != nil {
+		return SavedSearch{}, err
+	}
+	if lastRun.Valid {
+		ss.LastRun = lastRun.Time.Local()
+	}
+	return ss, nil
+}
+
+// DeleteSavedSearch removes the saved search with the name
+func (r Repo) DeleteSavedSearch(ctx context.Context, name string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM saved_search WHERE name = (?)", name)
+	if err != nil {
+		return fmt.Errorf("deleting saved search %q: %w", name, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting saved search %q: %w", name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("saved search %q: %w", name, ErrNotFound)
+	}
+
+	return nil
+}
+
+// ResolveSearch returns the query of the saved search named by a term of
+// "@name" and marks the saved search as run. Other terms are returned as is.
+func (r Repo) ResolveSearch(ctx context.Context, term string) (string, error) {
+	if !strings.HasPrefix(term, "@") {
+		return term, nil
+	}
+
+	ss, err := r.GetSavedSearch(ctx, strings.TrimPrefix(term, "@"))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE saved_search SET last_run = (?) WHERE name = (?)",
+		clock(), ss.Name)
+	if err != nil {
+		return "", fmt.Errorf("marking saved search %q as run: %w", ss.Name, err)
+	}
+
+	return ss.Query, nil
+}
+
+// Changed counts the notes matching the saved search that were modified since
+// it last ran. All matching notes count when it never ran.
+func (ss SavedSearch) Changed(ctx context.Context, r Repo) (int, error) {
+	var since interface{}
+	if !ss.LastRun.IsZero() {
+		since = ss.LastRun
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		FROM note_fts
+		INNER JOIN note_rev AS nr ON nr.rowid = note_fts.note_rev_rowid
+		WHERE note_fts.blob_body MATCH (?)
+		AND ((?) IS NULL OR julianday(nr.timestamp) > julianday(?))`,
+		ss.Query, since, since)
+
+	var n int
+	if err := row.Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting changes of saved search %q: %w", ss.Name, checkQueryError(ss.Query, err))
+	}
+	return n, nil
+}
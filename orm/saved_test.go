@@ -0,0 +1,84 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearches(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"oncall handoff",
+		"incident: disk full",
+		"grocery list",
+	})
+
+	_, err := repo.GetSavedSearch(ctx, "oncall")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	require.ErrorIs(t, repo.SaveSearch(ctx, orm.SavedSearch{Name: "bad", Query: "10.0.3.1"}), orm.ErrBadQuery)
+
+	require.NoError(t, repo.SaveSearch(ctx, orm.SavedSearch{Name: "oncall", Query: "oncall OR incident"}))
+	require.NoError(t, repo.SaveSearch(ctx, orm.SavedSearch{Name: "food", Query: "grocery"}))
+
+	searches, err := repo.GetSavedSearches(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []orm.SavedSearch{
+		{Name: "food", Query: "grocery"},
+		{Name: "oncall", Query: "oncall OR incident"},
+	}, searches)
+
+	// notes matching a saved search that never ran are all changed
+	changed, err := searches[1].Changed(ctx, repo)
+	require.NoError(t, err)
+	require.Equal(t, 2, changed)
+
+	// terms that don't refer to a saved search are kept
+	query, err := repo.ResolveSearch(ctx, "oncall")
+	require.NoError(t, err)
+	require.Equal(t, "oncall", query)
+
+	query, err = repo.ResolveSearch(ctx, "@oncall")
+	require.NoError(t, err)
+	require.Equal(t, "oncall OR incident", query)
+
+	_, err = repo.ResolveSearch(ctx, "@bogus")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	oncall, err := repo.GetSavedSearch(ctx, "oncall")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(4, 0).Local(), oncall.LastRun)
+
+	changed, err = oncall.Changed(ctx, repo)
+	require.NoError(t, err)
+	require.Equal(t, 0, changed)
+
+	_, err = revs[1].UpdateBlob(ctx, repo, bytes.NewBufferString("incident: disk full again"))
+	require.NoError(t, err)
+
+	changed, err = oncall.Changed(ctx, repo)
+	require.NoError(t, err)
+	require.Equal(t, 1, changed)
+
+	// replacing the query of a saved search resets its last run
+	require.NoError(t, repo.SaveSearch(ctx, orm.SavedSearch{Name: "oncall", Query: "handoff"}))
+	oncall, err = repo.GetSavedSearch(ctx, "oncall")
+	require.NoError(t, err)
+	require.Equal(t, orm.SavedSearch{Name: "oncall", Query: "handoff"}, oncall)
+
+	require.NoError(t, repo.DeleteSavedSearch(ctx, "oncall"))
+	require.ErrorIs(t, repo.DeleteSavedSearch(ctx, "oncall"), orm.ErrNotFound)
+}
@@ -0,0 +1,310 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadQuery is wrapped by errors for search queries that can't be parsed
+	ErrBadQuery = errors.New("bad search query")
+	// ErrHistoryDisabled is returned when searching the history of notes
+	// without enabling the history index config
+	ErrHistoryDisabled = errors.New("history index is disabled")
+)
+
+// QuerySyntaxError explains why a full text search query is invalid
+type QuerySyntaxError struct {
+	Query string
+	// Reason is the error reported by SQLite
+	Reason string
+	// Hint suggests how to fix the query
+	Hint string
+}
+
+func (qse QuerySyntaxError) Error() string {
+	msg := fmt.Sprintf("invalid search query %q: %s", qse.Query, qse.Reason)
+	if qse.Hint != "" {
+		msg += " (" + qse.Hint + ")"
+	}
+	return msg
+}
+
+func (qse QuerySyntaxError) Unwrap() error { return ErrBadQuery }
+
+// ftsSyntaxHints map fragments of SQLite FTS5 errors to advice for users
+var ftsSyntaxHints = []struct {
+	fragment string
+	hint     string
+}{
+	{"syntax error", `wrap terms containing punctuation in double quotes, e.g. "10.0.3.1" or "k8s-prod"`},
+	{"unterminated string", "close the double quote of the phrase"},
+	{"no such column", `a word followed by a colon filters by column, wrap it in double quotes to search for it, e.g. "todo:"`},
+	{"unknown special query", "queries can't start with an asterisk"},
+}
+
+// checkQueryError converts FTS5 query errors into a QuerySyntaxError
+func checkQueryError(query string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	for _, h := range ftsSyntaxHints {
+		if strings.Contains(msg, h.fragment) {
+			return QuerySyntaxError{
+				Query:  query,
+				Reason: strings.TrimPrefix(msg, "fts5: "),
+				Hint:   h.hint,
+			}
+		}
+	}
+
+	return err
+}
+
+// FTSResult is the result of a full text search of the blob table
+type FTSResult struct {
+	noteRevRowID int64
+	modified     time.Time
+	SHA256       string
+	BM25         float32
+	// Score is the BM25 score weighed by the ranking. Like BM25, the lower
+	// the score, the better the match.
+	Score   float32
+	Snippet string
+	// Line is the first line of the blob containing a match, starting at 1
+	Line int
+}
+
+func (ftsr FTSResult) GetNoteRev(ctx context.Context, repo Repo) (NoteRev, error) {
+	row := repo.db.QueryRowContext(ctx,
+		`SELECT note_id, blob_sha256, timestamp
+		FROM note_rev
+		WHERE rowid = (?)`,
+		ftsr.noteRevRowID)
+	var nr NoteRev
+	if err := row.Scan(&nr.ID, &nr.SHA256, &nr.Timestamp); err != nil {
+		return NoteRev{}, fmt.Errorf("scanning blob fts reults: %w", err)
+	}
+
+	nr.Timestamp = nr.Timestamp.Local()
+	return nr, nil
+}
+
+// SearchOptions refine a full text search
+type SearchOptions struct {
+	// Limit is the maximum number of results. Zero means no limit.
+	Limit int
+	// Offset skips the first results, for paging with Limit
+	Offset int
+	// HighlightStart and HighlightEnd surround the matches in the snippet.
+	// They default to pointing emojis.
+	HighlightStart string
+	HighlightEnd   string
+	// History searches every revision of the notes instead of the current
+	// revisions. It requires the history index config.
+	History bool
+	// Ranking orders the results, the ranking configured in the nest when nil
+	Ranking *Ranking
+}
+
+// checkPaging returns an error when the limit or offset is negative
+func (opts SearchOptions) checkPaging() error {
+	if opts.Limit < 0 {
+		return fmt.Errorf("search limit %d can't be negative", opts.Limit)
+	}
+	if opts.Offset < 0 {
+		return fmt.Errorf("search offset %d can't be negative", opts.Offset)
+	}
+	return nil
+}
+
+// Ranking blends the BM25 score of full text search results with how
+// recently the notes were modified and whether they match in their first
+// line, usually the title
+type Ranking struct {
+	// RecencyWeight is the boost of a note modified right now. A weight of 1
+	// doubles its score. Zero ranks by BM25 only.
+	RecencyWeight float64
+	// HalfLife is the number of days after which the recency boost halves
+	HalfLife float64
+	// TitleBoost multiplies the score of notes matching in their first line
+	TitleBoost float64
+}
+
+// BM25Ranking orders full text search results by their BM25 score only
+var BM25Ranking = Ranking{TitleBoost: 1}
+
+// score weighs the BM25 score of a result
+func (rk Ranking) score(bm25 float32, line int, age time.Duration) float32 {
+	boost := 1.0
+	if rk.HalfLife > 0 {
+		days := math.Max(age.Hours()/24, 0)
+		boost += rk.RecencyWeight * math.Pow(0.5, days/rk.HalfLife)
+	}
+	if line == 1 && rk.TitleBoost > 0 {
+		boost *= rk.TitleBoost
+	}
+	// BM25 scores are negative, so boosting makes them lower
+	return float32(float64(bm25) * boost)
+}
+
+// GetRanking returns the ranking configured in the nest
+func (r Repo) GetRanking(ctx context.Context) (Ranking, error) {
+	var rk Ranking
+	for key, dst := range map[ConfigKey]*float64{
+		ConfigRankRecency:    &rk.RecencyWeight,
+		ConfigRankHalfLife:   &rk.HalfLife,
+		ConfigRankTitleBoost: &rk.TitleBoost,
+	} {
+		val, err := r.GetConfig(ctx, key)
+		if err != nil {
+			return Ranking{}, fmt.Errorf("getting %s config: %w", key, err)
+		}
+		if *dst, err = strconv.ParseFloat(val, 64); err != nil {
+			return Ranking{}, fmt.Errorf("parsing %s config %q: %w", key, val, err)
+		}
+	}
+	return rk, nil
+}
+
+// ParseRanking overrides the weights of a ranking with a comma separated
+// list such as "recency=2,halflife=7,title=1". A spec of "bm25" orders by
+// BM25 only.
+func ParseRanking(base Ranking, spec string) (Ranking, error) {
+	if spec == "bm25" {
+		return BM25Ranking, nil
+	}
+
+	rk := base
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(field, "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return Ranking{}, fmt.Errorf("ranking weight %q must be a positive number", field)
+		}
+
+		switch strings.TrimSpace(name) {
+		case "recency":
+			rk.RecencyWeight = weight
+		case "halflife":
+			rk.HalfLife = weight
+		case "title":
+			rk.TitleBoost = weight
+		default:
+			return Ranking{}, fmt.Errorf("unknown ranking weight %q, expected recency, halflife or title", name)
+		}
+	}
+
+	return rk, nil
+}
+
+func (r Repo) FullTextSearch(ctx context.Context, searchTerm string) ([]FTSResult, error) {
+	return r.FullTextSearchOpts(ctx, searchTerm, SearchOptions{})
+}
+
+// FullTextSearchOpts searches the current note revisions, best match first
+func (r Repo) FullTextSearchOpts(ctx context.Context, searchTerm string, opts SearchOptions) ([]FTSResult, error) {
+	if err := opts.checkPaging(); err != nil {
+		return nil, err
+	}
+	if opts.HighlightStart == "" && opts.HighlightEnd == "" {
+		opts.HighlightStart, opts.HighlightEnd = "👉 ", " 👈"
+	}
+
+	table := "note_fts"
+	if opts.History {
+		enabled, err := r.GetConfig(ctx, ConfigHistoryIndex)
+		if err != nil {
+			return nil, fmt.Errorf("getting history index config: %w", err)
+		}
+		if enabled != "true" {
+			return nil, ErrHistoryDisabled
+		}
+		table = "note_history_fts"
+	}
+
+	ranking := opts.Ranking
+	if ranking == nil {
+		rk, err := r.GetRanking(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ranking = &rk
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT
+			%[1]s.note_rev_rowid,
+			nr.timestamp,
+			%[1]s.blob_sha256,
+			bm25(%[1]s, 0, 1.0),
+			snippet(%[1]s, -1, ?, ?, "...", 20),
+			highlight(%[1]s, 2, char(2), char(3))
+		FROM %[1]s
+		INNER JOIN note_rev AS nr ON nr.rowid = %[1]s.note_rev_rowid
+		WHERE %[1]s.blob_body MATCH (?)
+		ORDER BY bm25(%[1]s, 0, 1.0), nr.rowid DESC;`, table),
+		opts.HighlightStart, opts.HighlightEnd, searchTerm)
+	if err != nil {
+		return nil, fmt.Errorf("querying notes: %w", checkQueryError(searchTerm, err))
+	}
+	defer rows.Close()
+
+	var (
+		results []FTSResult
+		now     = clock()
+	)
+
+	for rows.Next() {
+		var (
+			b         FTSResult
+			highlight string
+		)
+		if err := rows.Scan(&b.noteRevRowID, &b.modified, &b.SHA256, &b.BM25, &b.Snippet, &highlight); err != nil {
+			return nil, fmt.Errorf("scanning blob fts reults: %w", err)
+		}
+		b.Line = matchLine(highlight)
+		b.Score = ranking.score(b.BM25, b.Line, now.Sub(b.modified))
+		results = append(results, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying notes: %w", checkQueryError(searchTerm, err))
+	}
+
+	// the ranking depends on every result, so results are paged afterwards
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score < results[j].Score })
+
+	if opts.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(results) {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// matchLine finds the line of the first highlighted match in a blob body
+// highlighted with the char(2) and char(3) markers
+func matchLine(highlight string) int {
+	i := strings.IndexByte(highlight, 2)
+	if i < 0 {
+		return 1
+	}
+	return strings.Count(highlight[:i], "\n") + 1
+}
@@ -0,0 +1,256 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFullTextSearchOpts(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"bird bird bird",
+		"bird bird",
+		"bird",
+	})
+
+	all, err := repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{Ranking: &orm.BM25Ranking})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	require.Equal(t, revs[0].SHA256, all[0].SHA256)
+
+	// pages of results keep the ranking
+	page, err := repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{Limit: 2, Ranking: &orm.BM25Ranking})
+	require.NoError(t, err)
+	require.Equal(t, all[:2], page)
+
+	page, err = repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{Limit: 2, Offset: 2, Ranking: &orm.BM25Ranking})
+	require.NoError(t, err)
+	require.Equal(t, all[2:], page)
+
+	// matches in snippets are surrounded by the highlight markers
+	results, err := repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{
+		HighlightStart: "<b>",
+		HighlightEnd:   "</b>",
+		Offset:         2,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "<b>bird</b>", results[0].Snippet)
+
+	// negative limits and offsets are rejected
+	_, err = repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{Offset: -1})
+	require.EqualError(t, err, "search offset -1 can't be negative")
+	_, err = repo.FullTextSearchOpts(ctx, "bird", orm.SearchOptions{Limit: -1})
+	require.EqualError(t, err, "search limit -1 can't be negative")
+	_, err = repo.QueryNotes(ctx, orm.Query{}, orm.SearchOptions{Offset: -1})
+	require.EqualError(t, err, "search offset -1 can't be negative")
+}
+
+func TestSearchRanking(t *testing.T) {
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	orm.SetClock(func() time.Time { return now })
+	defer orm.SetClock(time.Now)
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// an old note full of keywords and a note with the keyword in its title
+	// modified yesterday
+	now = now.AddDate(-1, 0, 0)
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Runbook\ndeploy deploy deploy deploy deploy",
+	})
+	now = now.AddDate(1, -1, 0)
+	revs = append(revs, ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"# Deploy\nfriday notes about the weekend",
+		"# Friday\nwe should deploy",
+	})...)
+	now = time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	sums := func(rk orm.Ranking) []string {
+		t.Helper()
+		results, err := repo.FullTextSearchOpts(ctx, "deploy", orm.SearchOptions{Ranking: &rk})
+		require.NoError(t, err)
+		var sums []string
+		for _, r := range results {
+			sums = append(sums, r.SHA256)
+		}
+		return sums
+	}
+
+	require.Equal(t, []string{revs[0].SHA256, revs[2].SHA256, revs[1].SHA256}, sums(orm.BM25Ranking))
+	require.Equal(t, []string{revs[1].SHA256, revs[0].SHA256, revs[2].SHA256}, sums(orm.Ranking{TitleBoost: 3}))
+	require.Equal(t, []string{revs[2].SHA256, revs[1].SHA256, revs[0].SHA256}, sums(orm.Ranking{RecencyWeight: 10, HalfLife: 30, TitleBoost: 1}))
+
+	// note queries with text terms are ranked the same way, unless sorted
+	querySums := func(query string, rk orm.Ranking) []string {
+		t.Helper()
+		q, err := orm.ParseQuery(query)
+		require.NoError(t, err)
+		results, err := repo.QueryNotes(ctx, q, orm.SearchOptions{Ranking: &rk})
+		require.NoError(t, err)
+		var sums []string
+		for _, r := range results {
+			sums = append(sums, r.SHA256)
+		}
+		return sums
+	}
+	for _, rk := range []orm.Ranking{orm.BM25Ranking, {TitleBoost: 3}, {RecencyWeight: 10, HalfLife: 30, TitleBoost: 1}} {
+		require.Equal(t, sums(rk), querySums("deploy", rk))
+	}
+	require.Equal(t, []string{revs[0].SHA256, revs[1].SHA256, revs[2].SHA256}, querySums("deploy sort:id", orm.Ranking{TitleBoost: 3}))
+
+	// the ranking defaults to the config
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigRankRecency, "0"))
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigRankTitleBoost, "1"))
+	rk, err := repo.GetRanking(ctx)
+	require.NoError(t, err)
+	require.Equal(t, orm.Ranking{RecencyWeight: 0, HalfLife: 30, TitleBoost: 1}, rk)
+
+	results, err := repo.FullTextSearch(ctx, "deploy")
+	require.NoError(t, err)
+	require.Equal(t, revs[0].SHA256, results[0].SHA256)
+
+	require.Error(t, repo.SetConfig(ctx, orm.ConfigRankHalfLife, "-1"))
+
+	rk, err = orm.ParseRanking(rk, "recency=2, title=1.5")
+	require.NoError(t, err)
+	require.Equal(t, orm.Ranking{RecencyWeight: 2, HalfLife: 30, TitleBoost: 1.5}, rk)
+
+	rk, err = orm.ParseRanking(rk, "bm25")
+	require.NoError(t, err)
+	require.Equal(t, orm.BM25Ranking, rk)
+
+	_, err = orm.ParseRanking(rk, "age=1")
+	require.Error(t, err)
+	_, err = orm.ParseRanking(rk, "title=x")
+	require.Error(t, err)
+}
+
+func TestFullTextSearchSyntaxError(t *testing.T) {
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"10.0.3.1",
+		`"unterminated`,
+		"todo: later",
+		"*",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := repo.FullTextSearch(ctx, query)
+			require.ErrorIs(t, err, orm.ErrBadQuery)
+
+			var qse orm.QuerySyntaxError
+			require.True(t, errors.As(err, &qse))
+			require.Equal(t, query, qse.Query)
+			require.NotEmpty(t, qse.Hint)
+		})
+	}
+}
+
+func TestFullTextSearchHistory(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"the old phrase",
+		"unrelated",
+	})
+	old := revs[0]
+
+	current, err := old.UpdateBlob(ctx, repo, bytes.NewBufferString("the new phrase"))
+	require.NoError(t, err)
+
+	_, err = repo.FullTextSearchOpts(ctx, "old", orm.SearchOptions{History: true})
+	require.ErrorIs(t, err, orm.ErrHistoryDisabled)
+
+	results, err := repo.FullTextSearch(ctx, "old")
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	// enabling the history index adds the existing revisions
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigHistoryIndex, "1"))
+	enabled, err := repo.GetConfig(ctx, orm.ConfigHistoryIndex)
+	require.NoError(t, err)
+	require.Equal(t, "true", enabled)
+
+	results, err = repo.FullTextSearchOpts(ctx, "old", orm.SearchOptions{History: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	nr, err := results[0].GetNoteRev(ctx, repo)
+	require.NoError(t, err)
+	require.Equal(t, old, nr)
+
+	// new revisions are indexed as they are created
+	latest, err := current.UpdateBlob(ctx, repo, bytes.NewBufferString("the phrase"))
+	require.NoError(t, err)
+
+	results, err = repo.FullTextSearchOpts(ctx, "phrase", orm.SearchOptions{History: true})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// squashed revisions are removed from the history index
+	require.NoError(t, repo.SquashNoteRevs(ctx, []orm.NoteRev{old, current}))
+	results, err = repo.FullTextSearchOpts(ctx, "phrase", orm.SearchOptions{History: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, latest.SHA256, results[0].SHA256)
+
+	// disabling the history index empties it
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigHistoryIndex, "false"))
+	require.NoError(t, repo.SetConfig(ctx, orm.ConfigHistoryIndex, "true"))
+	results, err = repo.FullTextSearchOpts(ctx, "phrase", orm.SearchOptions{History: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	require.Error(t, repo.SetConfig(ctx, orm.ConfigHistoryIndex, "maybe"))
+}
+
+func TestFindNoteRev(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	revs := ormtest.InsertTestNotes(t, ctx, repo, []string{"first"})
+	second, err := revs[0].UpdateBlob(ctx, repo, bytes.NewBufferString("second"))
+	require.NoError(t, err)
+
+	nr, err := repo.FindNoteRev(ctx, revs[0].ID, revs[0].SHA256[:8])
+	require.NoError(t, err)
+	require.Equal(t, revs[0], nr)
+
+	nr, err = repo.FindNoteRev(ctx, second.ID, second.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, second, nr)
+
+	_, err = repo.FindNoteRev(ctx, second.ID, "zz")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+}
@@ -0,0 +1,90 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// AddStopWords excludes the words from the word cloud and related notes.
+// Words are case insensitive and adding a stop word twice has no effect.
+func (r Repo) AddStopWords(ctx context.Context, words ...string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting add stop words tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, w := range words {
+		_, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO stop_words (word) VALUES (?)",
+			strings.ToLower(w))
+		if err != nil {
+			return fmt.Errorf("adding stop word %q: %w", w, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commiting add stop words tx: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStopWords includes the words in the word cloud again. None of the
+// words are removed unless they are all stop words.
+func (r Repo) DeleteStopWords(ctx context.Context, words ...string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting delete stop words tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, w := range words {
+		res, err := tx.ExecContext(ctx,
+			"DELETE FROM stop_words WHERE word = (?)",
+			strings.ToLower(w))
+		if err != nil {
+			return fmt.Errorf("deleting stop word %q: %w", w, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("deleting stop word %q: %w", w, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("stop word %q: %w", w, ErrNotFound)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commiting delete stop words tx: %w", err)
+	}
+
+	return nil
+}
+
+// GetStopWords returns all stop words in alphabetical order
+func (r Repo) GetStopWords(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT word FROM stop_words ORDER BY word")
+	if err != nil {
+		return nil, fmt.Errorf("querying stop words: %w", err)
+	}
+	defer rows.Close()
+
+	var words []string
+	for rows.Next() {
+		var w string
+		if err := rows.Scan(&w); err != nil {
+			return nil, fmt.Errorf("scanning stop words: %w", err)
+		}
+		words = append(words, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying stop words: %w", err)
+	}
+
+	return words, nil
+}
@@ -0,0 +1,243 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ftsOperators are the FTS5 query keywords that are never corrected
+var ftsOperators = map[string]bool{"AND": true, "OR": true, "NOT": true, "NEAR": true}
+
+// vocabTerms returns how many times each term appears in the current note
+// revisions
+func (r Repo) vocabTerms(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT term, cnt
+		FROM note_fts_vocab_cols
+		WHERE col = "blob_body"`)
+	if err != nil {
+		return nil, fmt.Errorf("querying vocabulary: %w", err)
+	}
+	defer rows.Close()
+
+	terms := map[string]int64{}
+	for rows.Next() {
+		var (
+			term string
+			cnt  int64
+		)
+		if err := rows.Scan(&term, &cnt); err != nil {
+			return nil, fmt.Errorf("scanning vocabulary: %w", err)
+		}
+		terms[term] = cnt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying vocabulary: %w", err)
+	}
+
+	return terms, nil
+}
+
+// queryWord is a word of a search query at a byte offset of the query
+type queryWord struct {
+	start, end int
+	text       string
+}
+
+// misspelledWords returns the words of an FTS5 query that aren't in the
+// vocabulary, except operators, prefixes and column filters
+func misspelledWords(query string, vocab map[string]int64) []queryWord {
+	var (
+		words   []queryWord
+		inQuote bool
+		start   = -1
+	)
+
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+
+	for i, r := range query + " " {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			w := queryWord{start: start, end: i, text: query[start:i]}
+			start = -1
+
+			switch {
+			case !inQuote && ftsOperators[w.text]:
+			case !inQuote && (r == '*' || r == ':'):
+			case vocab[strings.ToLower(w.text)] > 0:
+			default:
+				words = append(words, w)
+			}
+		}
+
+		if r == '"' {
+			inQuote = !inQuote
+		}
+	}
+
+	return words
+}
+
+// maxEdits is the largest edit distance of a correction of a word
+func maxEdits(word string) int {
+	if len([]rune(word)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// editDistance is the optimal string alignment distance of two words: the
+// number of insertions, deletions, substitutions and transpositions of
+// adjacent letters to turn one into the other
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// rows i-2, i-1 and i of the distance matrix
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost
+			if del := prev[j] + 1; del < d {
+				d = del
+			}
+			if ins := cur[j-1] + 1; ins < d {
+				d = ins
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if tr := prev2[j-2] + 1; tr < d {
+					d = tr
+				}
+			}
+			cur[j] = d
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+// corrections returns up to n terms of the vocabulary close to the word,
+// the fewest edits first and the most frequent first among equally close
+// terms
+func corrections(word string, vocab map[string]int64, n int) []string {
+	word = strings.ToLower(word)
+	limit := maxEdits(word)
+	length := len([]rune(word))
+
+	type candidate struct {
+		term  string
+		edits int
+		count int64
+	}
+	var candidates []candidate
+	for term, count := range vocab {
+		if diff := len([]rune(term)) - length; diff > limit || -diff > limit {
+			continue
+		}
+		if edits := editDistance(word, term); edits <= limit {
+			candidates = append(candidates, candidate{term: term, edits: edits, count: count})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.edits != cj.edits {
+			return ci.edits < cj.edits
+		}
+		if ci.count != cj.count {
+			return ci.count > cj.count
+		}
+		return ci.term < cj.term
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	terms := make([]string, len(candidates))
+	for i, c := range candidates {
+		terms[i] = c.term
+	}
+	return terms
+}
+
+// SuggestQueries returns up to n corrections of a full text search query,
+// best first, for use when the query matches nothing. Words that aren't in
+// any current note revision are replaced with similar words of the notes.
+// Operators, prefix searches and column filters are left as they are. It
+// returns no suggestions when every word is found or none can be corrected.
+func (r Repo) SuggestQueries(ctx context.Context, query string, n int) ([]string, error) {
+	vocab, err := r.vocabTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	words := misspelledWords(query, vocab)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	fixes := make([][]string, len(words))
+	corrected := false
+	for i, w := range words {
+		fixes[i] = corrections(w.text, vocab, n)
+		corrected = corrected || len(fixes[i]) > 0
+	}
+	if !corrected {
+		return nil, nil
+	}
+
+	// the k-th suggestion uses the k-th best correction of each word, or
+	// the last one when a word has fewer corrections
+	var (
+		suggestions []string
+		seen        = map[string]bool{}
+	)
+	for k := 0; k < n; k++ {
+		var (
+			b    strings.Builder
+			last int
+		)
+		for i, w := range words {
+			b.WriteString(query[last:w.start])
+			switch {
+			case len(fixes[i]) == 0:
+				b.WriteString(w.text)
+			case k < len(fixes[i]):
+				b.WriteString(fixes[i][k])
+			default:
+				b.WriteString(fixes[i][len(fixes[i])-1])
+			}
+			last = w.end
+		}
+		b.WriteString(query[last:])
+
+		if s := b.String(); !seen[s] {
+			seen[s] = true
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,48 @@
+package orm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestQueries(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"kubernetes cluster upgrade",
+		"kubernetes nodes",
+		"cluster clusters",
+		"grafana dashboards",
+	})
+
+	for _, tc := range []struct {
+		query string
+		n     int
+		want  []string
+	}{
+		{query: "kuberentes", n: 3, want: []string{"kubernetes"}},
+		{query: "Kubernets clustr", n: 1, want: []string{"kubernetes cluster"}},
+		// the more frequent term comes first among equally close terms
+		{query: "clustera", n: 2, want: []string{"cluster", "clusters"}},
+		{query: `"kuberentes nodes" OR grafna`, n: 1, want: []string{`"kubernetes nodes" OR grafana`}},
+		{query: "kuberentes AND xyzzy", n: 1, want: []string{"kubernetes AND xyzzy"}},
+		{query: "kubernetes", n: 3},
+		{query: "kuber*", n: 3},
+		{query: "xyzzy", n: 3},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			suggestions, err := repo.SuggestQueries(ctx, tc.query, tc.n)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, suggestions)
+		})
+	}
+}
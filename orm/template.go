@@ -0,0 +1,139 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"text/template"
+)
+
+// Template is a named skeleton for new notes stored in the nest. The body is
+// a Go text/template rendered with TemplateData.
+type Template struct {
+	Name string
+	Body string
+}
+
+// SaveTemplate creates the template, or replaces the body of an existing
+// template with the same name
+func (r Repo) SaveTemplate(ctx context.Context, t Template) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO template (name, body) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET body = excluded.body`,
+		t.Name, t.Body)
+	if err != nil {
+		return fmt.Errorf("saving template %q: %w", t.Name, err)
+	}
+	return nil
+}
+
+// GetTemplate returns the template with the name
+func (r Repo) GetTemplate(ctx context.Context, name string) (Template, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT name, body FROM template WHERE name = (?)", name)
+
+	var t Template
+	if err := row.Scan(&t.Name, &t.Body); errors.Is(err, sql.ErrNoRows) {
+		return Template{}, fmt.Errorf("template %q: %w", name, ErrNotFound)
+	} else if err != nil {
+		return Template{}, fmt.Errorf("getting template %q: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// GetTemplates returns all templates ordered by name
+func (r Repo) GetTemplates(ctx context.Context) ([]Template, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT name, body FROM template ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("querying templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		var t Template
+		if err := rows.Scan(&t.Name, &t.Body); err != nil {
+			return nil, fmt.Errorf("scanning templates: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+// Render executes the template with the template data
+func (t Template) Render(w io.Writer, data TemplateData) error {
+	return RenderTemplate(w, t.Body, data)
+}
+
+// TemplateData are the placeholders available to note templates
+type TemplateData struct {
+	// Date is the current date, e.g. 2006-01-02
+	Date string
+	// Time is the current time, e.g. 15:04
+	Time string
+	// Weekday is the name of the current day, e.g. Monday
+	Weekday string
+	// User is the name of the current user
+	User string
+	// Prompt asks the user for the value of a field named by the template
+	// with {{ prompt "Field" }}. Each field is only asked for once.
+	Prompt func(field string) (string, error)
+}
+
+// NewTemplateData derives the template placeholders from the clock
+func NewTemplateData() TemplateData {
+	now := clock().Local()
+
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	return TemplateData{
+		Date:    now.Format(DayLayout),
+		Time:    now.Format("15:04"),
+		Weekday: now.Weekday().String(),
+		User:    name,
+	}
+}
+
+// RenderTemplate executes the Go text/template body with the template data
+func RenderTemplate(w io.Writer, body string, data TemplateData) error {
+	answers := map[string]string{}
+	prompt := func(field string) (string, error) {
+		if a, ok := answers[field]; ok {
+			return a, nil
+		}
+		if data.Prompt == nil {
+			return "", fmt.Errorf("no prompt available for field %q", field)
+		}
+
+		a, err := data.Prompt(field)
+		if err != nil {
+			return "", fmt.Errorf("prompting for field %q: %w", field, err)
+		}
+		answers[field] = a
+		return a, nil
+	}
+
+	tmpl, err := template.New("note").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"prompt": prompt}).
+		Parse(body)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package orm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := repo.GetTemplate(ctx, "meeting")
+	require.ErrorIs(t, err, orm.ErrNotFound)
+
+	meeting := orm.Template{
+		Name: "meeting",
+		Body: "# {{ prompt \"Topic\" }} {{ .Date }} {{ .Time }}\n\nwith {{ prompt \"Attendees\" }}\n\n## {{ prompt \"Topic\" }} notes\n",
+	}
+	require.NoError(t, repo.SaveTemplate(ctx, orm.Template{Name: "meeting", Body: "draft"}))
+	require.NoError(t, repo.SaveTemplate(ctx, meeting))
+	require.NoError(t, repo.SaveTemplate(ctx, orm.Template{Name: "incident", Body: "# Incident"}))
+
+	templates, err := repo.GetTemplates(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"incident", "meeting"}, []string{templates[0].Name, templates[1].Name})
+
+	got, err := repo.GetTemplate(ctx, "meeting")
+	require.NoError(t, err)
+	require.Equal(t, meeting, got)
+
+	var prompted []string
+	data := orm.NewTemplateData()
+	data.Prompt = func(field string) (string, error) {
+		prompted = append(prompted, field)
+		return map[string]string{"Topic": "Roadmap", "Attendees": "the team"}[field], nil
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, got.Render(buf, data))
+
+	now := time.Unix(1, 0).Local()
+	require.Equal(t,
+		"# Roadmap "+now.Format(orm.DayLayout)+" "+now.Format("15:04")+"\n\nwith the team\n\n## Roadmap notes\n",
+		buf.String())
+	require.Equal(t, []string{"Topic", "Attendees"}, prompted)
+
+	// templates with fields can only be rendered with a prompt
+	require.Error(t, got.Render(buf, orm.TemplateData{}))
+}
+
+func TestRenderTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := orm.RenderTemplate(buf, "{{ .Nope }}", orm.TemplateData{})
+	require.Error(t, err)
+
+	buf.Reset()
+	require.NoError(t, orm.RenderTemplate(buf, "by {{ .User }} at {{ .Time }}", orm.TemplateData{User: "me", Time: "12:00"}))
+	require.Equal(t, "by me at 12:00", buf.String())
+}
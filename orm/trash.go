@@ -0,0 +1,123 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TrashedNote is the current revision of a note in the trash
+type TrashedNote struct {
+	NoteRev
+	TrashedAt time.Time
+}
+
+// TrashNote moves the note to the trash. Trashed notes are left out of note
+// listings, searches and queries but keep all their revisions.
+func (r Repo) TrashNote(ctx context.Context, id int64) error {
+	if _, err := r.GetCurrentNoteRev(ctx, id); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting trash note tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := trashNoteTx(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commiting trash note tx: %w", err)
+	}
+
+	return nil
+}
+
+// trashNoteTx moves a note to the trash within a transaction, unless it is
+// already trashed
+func trashNoteTx(ctx context.Context, tx *sql.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO note_trash (note_id, trashed_at) VALUES (?, ?)",
+		id, clock().UTC())
+	if err != nil {
+		return fmt.Errorf("trashing note %d: %w", id, err)
+	}
+	return nil
+}
+
+// RestoreNote takes the note out of the trash
+func (r Repo) RestoreNote(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM note_trash WHERE note_id = (?)", id)
+	if err != nil {
+		return fmt.Errorf("restoring note %d: %w", id, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("restoring note %d: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("trashed note %d: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// restoreNoteTx takes a note out of the trash within a transaction, if it
+// is trashed
+func restoreNoteTx(ctx context.Context, tx *sql.Tx, id int64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_trash WHERE note_id = (?)", id); err != nil {
+		return fmt.Errorf("restoring note %d: %w", id, err)
+	}
+	return nil
+}
+
+// IsTrashed reports whether the note is in the trash
+func (r Repo) IsTrashed(ctx context.Context, id int64) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM note_trash WHERE note_id = (?)", id).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("checking if note %d is trashed: %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// GetTrashedNotes returns the current revisions of the trashed notes, the
+// most recently trashed first
+func (r Repo) GetTrashedNotes(ctx context.Context) ([]TrashedNote, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT nr.note_id, nr.blob_sha256, nr.timestamp, note_trash.trashed_at
+		FROM note_trash
+		INNER JOIN note_rev AS nr ON nr.rowid = (
+			SELECT MAX(rowid)
+			FROM note_rev
+			WHERE note_id = note_trash.note_id
+		)
+		ORDER BY julianday(note_trash.trashed_at) DESC, nr.note_id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("querying trashed notes: %w", err)
+	}
+	defer rows.Close()
+
+	var trashed []TrashedNote
+	for rows.Next() {
+		var tn TrashedNote
+		if err := rows.Scan(&tn.ID, &tn.SHA256, &tn.Timestamp, &tn.TrashedAt); err != nil {
+			return nil, fmt.Errorf("scanning trashed notes: %w", err)
+		}
+		tn.Timestamp = tn.Timestamp.Local()
+		tn.TrashedAt = tn.TrashedAt.Local()
+		trashed = append(trashed, tn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying trashed notes: %w", err)
+	}
+
+	return trashed, nil
+}
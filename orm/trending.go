@@ -0,0 +1,230 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a period of time. A zero Since or Until leaves the window
+// open on that side.
+type TimeWindow struct {
+	Since time.Time
+	// Until is the end of the window, excluded
+	Until time.Time
+}
+
+// IsZero reports whether the window covers all time
+func (tw TimeWindow) IsZero() bool {
+	return tw.Since.IsZero() && tw.Until.IsZero()
+}
+
+// Contains reports whether the time is within the window
+func (tw TimeWindow) Contains(t time.Time) bool {
+	return (tw.Since.IsZero() || !t.Before(tw.Since)) && (tw.Until.IsZero() || t.Before(tw.Until))
+}
+
+// ParseTimeWindow parses a window starting a duration ago, such as "36h", "7d"
+// or "2w", or on a day such as "2026-01-01", or between two days such as
+// "2026-01-01..2026-01-31". Days are in the local time zone and ranges
+// include their last day.
+func ParseTimeWindow(since, between string) (TimeWindow, error) {
+	switch {
+	case since != "" && between != "":
+		return TimeWindow{}, errors.New("a time window is either since a time or between two days")
+
+	case since != "":
+		if day, err := time.ParseInLocation(DayLayout, since, time.Local); err == nil {
+			return TimeWindow{Since: day}, nil
+		}
+		d, err := parseDays(since)
+		if err != nil {
+			return TimeWindow{}, err
+		}
+		return TimeWindow{Since: clock().Add(-d)}, nil
+
+	case between != "":
+		lo, hi, ok := strings.Cut(between, "..")
+		if !ok {
+			return TimeWindow{}, fmt.Errorf("invalid range %q, expected YYYY-MM-DD..YYYY-MM-DD", between)
+		}
+		first, err := time.ParseInLocation(DayLayout, lo, time.Local)
+		if err != nil {
+			return TimeWindow{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", lo)
+		}
+		last, err := time.ParseInLocation(DayLayout, hi, time.Local)
+		if err != nil {
+			return TimeWindow{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", hi)
+		}
+		if last.Before(first) {
+			return TimeWindow{}, fmt.Errorf("range %q ends before it starts", between)
+		}
+		return TimeWindow{Since: first, Until: last.AddDate(0, 0, 1)}, nil
+	}
+
+	return TimeWindow{}, nil
+}
+
+// dayUnits are the units of durations in days that time.ParseDuration lacks
+var dayUnits = map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+// parseDays parses a duration that can also be given in days or weeks
+func parseDays(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration %q, expected e.g. 36h, 7d or 2w", s)
+
+	if unit, ok := dayUnits[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, invalid
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, invalid
+	}
+	return d, nil
+}
+
+// InstancesIn returns the places the term occurs in the notes last modified
+// within the window, like the counts of WordCloudTermsIn
+func (wct WCTerm) InstancesIn(ctx context.Context, repo Repo, tw TimeWindow) ([]NoteRev, error) {
+	instances, err := wct.Instances(ctx, repo)
+	if err != nil || tw.IsZero() {
+		return instances, err
+	}
+
+	var within []NoteRev
+	for _, nr := range instances {
+		if tw.Contains(nr.Timestamp) {
+			within = append(within, nr)
+		}
+	}
+	return within, nil
+}
+
+// WordCloudTermsIn returns the terms of the word cloud of the notes last
+// modified within the window, most frequent first
+func (r Repo) WordCloudTermsIn(ctx context.Context, tw TimeWindow) ([]WCTerm, error) {
+	if tw.IsZero() {
+		return r.WordCloudTerms(ctx)
+	}
+
+	filter, args, err := r.wordCloudFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	const layout = "2006-01-02 15:04:05"
+	if !tw.Since.IsZero() {
+		filter += `
+		AND julianday(nr.timestamp) >= julianday(?)`
+		args = append(args, tw.Since.UTC().Format(layout))
+	}
+	if !tw.Until.IsZero() {
+		filter += `
+		AND julianday(nr.timestamp) < julianday(?)`
+		args = append(args, tw.Until.UTC().Format(layout))
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT
+			term,
+			COUNT(DISTINCT doc),
+			COUNT(*)
+		FROM note_fts_vocab_instances
+		INNER JOIN note_fts
+			ON note_fts_vocab_instances.doc = note_fts.rowid
+		INNER JOIN note_rev AS nr
+			ON note_fts.note_rev_rowid = nr.rowid
+		WHERE col = 'blob_body'
+		AND `+filter+`
+		GROUP BY term
+		ORDER BY COUNT(*) DESC, term`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying word cloud terms: %w", err)
+	}
+	defer rows.Close()
+
+	var results []WCTerm
+	for rows.Next() {
+		var wc WCTerm
+		if err := rows.Scan(&wc.Term, &wc.NoteCount, &wc.InstanceCount); err != nil {
+			return nil, fmt.Errorf("scanning word cloud term reults: %w", err)
+		}
+		results = append(results, wc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying word cloud terms: %w", err)
+	}
+
+	return results, nil
+}
+
+// TrendingTerm is a term used more often within a time window than in all
+// notes
+type TrendingTerm struct {
+	// WCTerm counts the term in the notes modified within the window
+	WCTerm
+	// BaselineCount is the number of instances of the term in all notes
+	BaselineCount int64
+	// Lift is how many times more frequent the term is within the window
+	// than in all notes, relative to all terms
+	Lift float64
+}
+
+// TrendingTerms returns the terms of the notes modified within the window
+// that are more frequent than in all notes, the most trending first
+func (r Repo) TrendingTerms(ctx context.Context, tw TimeWindow) ([]TrendingTerm, error) {
+	if tw.IsZero() {
+		return nil, errors.New("trending terms require a time window")
+	}
+
+	window, err := r.WordCloudTermsIn(ctx, tw)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := r.WordCloudTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var windowTotal, baselineTotal int64
+	baselineCounts := make(map[string]int64, len(baseline))
+	for _, t := range window {
+		windowTotal += t.InstanceCount
+	}
+	for _, t := range baseline {
+		baselineTotal += t.InstanceCount
+		baselineCounts[t.Term] = t.InstanceCount
+	}
+
+	var trending []TrendingTerm
+	for _, t := range window {
+		b := baselineCounts[t.Term]
+		if b == 0 {
+			continue
+		}
+		// the share of the term in the window over its share in all notes
+		lift := float64(t.InstanceCount*baselineTotal) / float64(b*windowTotal)
+		if lift > 1 {
+			trending = append(trending, TrendingTerm{WCTerm: t, BaselineCount: b, Lift: lift})
+		}
+	}
+
+	sort.SliceStable(trending, func(i, j int) bool {
+		if trending[i].Lift != trending[j].Lift {
+			return trending[i].Lift > trending[j].Lift
+		}
+		return trending[i].InstanceCount > trending[j].InstanceCount
+	})
+
+	return trending, nil
+}
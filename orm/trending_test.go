@@ -0,0 +1,108 @@
+package orm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokstad/nestable/internal/ormtest"
+	"github.com/pokstad/nestable/orm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation(orm.DayLayout, s, time.Local)
+		require.NoError(t, err)
+		return d
+	}
+
+	for _, tc := range []struct {
+		since, between string
+		want           orm.TimeWindow
+	}{
+		{},
+		{since: "2026-01-01", want: orm.TimeWindow{Since: day("2026-01-01")}},
+		{between: "2026-01-01..2026-01-31", want: orm.TimeWindow{Since: day("2026-01-01"), Until: day("2026-02-01")}},
+		{since: "7d", want: orm.TimeWindow{Since: time.Unix(1, 0).Add(-7 * 24 * time.Hour)}},
+		{since: "2w", want: orm.TimeWindow{Since: time.Unix(2, 0).Add(-14 * 24 * time.Hour)}},
+		{since: "36h", want: orm.TimeWindow{Since: time.Unix(3, 0).Add(-36 * time.Hour)}},
+	} {
+		tw, err := orm.ParseTimeWindow(tc.since, tc.between)
+		require.NoError(t, err)
+		require.True(t, tc.want.Since.Equal(tw.Since), "since %q between %q", tc.since, tc.between)
+		require.True(t, tc.want.Until.Equal(tw.Until), "since %q between %q", tc.since, tc.between)
+	}
+
+	for _, tc := range [][2]string{
+		{"7", ""},
+		{"-7d", ""},
+		{"xd", ""},
+		{"", "2026-01-01"},
+		{"", "2026-02-01..2026-01-01"},
+		{"", "2026-01-01..2026-1-31"},
+		{"7d", "2026-01-01..2026-01-31"},
+	} {
+		_, err := orm.ParseTimeWindow(tc[0], tc[1])
+		require.Error(t, err, "since %q between %q", tc[0], tc[1])
+	}
+}
+
+func TestTrendingTerms(t *testing.T) {
+	clockCleanup := ormtest.MockClock()
+	defer clockCleanup()
+
+	repo, cleanup := ormtest.TempTestRepo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// the mock clock modifies note i at i seconds after the epoch
+	ormtest.InsertTestNotes(t, ctx, repo, []string{
+		"cluster nodes",
+		"cluster upgrade",
+		"grafana dashboards grafana",
+		"grafana alerts cluster",
+	})
+	window := orm.TimeWindow{Since: time.Unix(3, 0), Until: time.Unix(5, 0)}
+
+	terms, err := repo.WordCloudTermsIn(ctx, window)
+	require.NoError(t, err)
+	require.Equal(t, []orm.WCTerm{
+		{Term: "grafana", NoteCount: 2, InstanceCount: 3},
+		{Term: "alerts", NoteCount: 1, InstanceCount: 1},
+		{Term: "cluster", NoteCount: 1, InstanceCount: 1},
+		{Term: "dashboards", NoteCount: 1, InstanceCount: 1},
+	}, terms)
+
+	terms, err = repo.WordCloudTermsIn(ctx, orm.TimeWindow{Until: time.Unix(2, 0)})
+	require.NoError(t, err)
+	require.Equal(t, []orm.WCTerm{
+		{Term: "cluster", NoteCount: 1, InstanceCount: 1},
+		{Term: "nodes", NoteCount: 1, InstanceCount: 1},
+	}, terms)
+
+	// the notes of a term are those counted in the window
+	instances, err := orm.WCTerm{Term: "cluster"}.InstancesIn(ctx, repo, window)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	require.Equal(t, int64(4), instances[0].ID)
+	instances, err = orm.WCTerm{Term: "cluster"}.InstancesIn(ctx, repo, orm.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, instances, 3)
+
+	trending, err := repo.TrendingTerms(ctx, window)
+	require.NoError(t, err)
+	// grafana makes half of the window and 3 out of 10 terms overall
+	require.Equal(t, []orm.TrendingTerm{
+		{WCTerm: orm.WCTerm{Term: "grafana", NoteCount: 2, InstanceCount: 3}, BaselineCount: 3, Lift: 10.0 / 6},
+		{WCTerm: orm.WCTerm{Term: "alerts", NoteCount: 1, InstanceCount: 1}, BaselineCount: 1, Lift: 10.0 / 6},
+		{WCTerm: orm.WCTerm{Term: "dashboards", NoteCount: 1, InstanceCount: 1}, BaselineCount: 1, Lift: 10.0 / 6},
+	}, trending)
+
+	_, err = repo.TrendingTerms(ctx, orm.TimeWindow{})
+	require.Error(t, err)
+}